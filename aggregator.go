@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/a-fleming/gator/internal/config"
+	"github.com/a-fleming/gator/internal/metrics"
+)
+
+// errAggregatorStopped is the cancellation cause when agg is shut down, so
+// that interrupted fetches aren't recorded as feed failures.
+var errAggregatorStopped = errors.New("aggregator stopped")
+
+type aggregateOptions struct {
+	concurrency     int
+	batchSize       int
+	feedTimeout     time.Duration
+	httpTimeoutSec  int
+	shutdownTimeout time.Duration
+	metricsAddr     string
+	// logLevel is the --log-level override, kept across config reloads
+	logLevel string
+}
+
+// scrapeSummary counts the outcome of one or more batches of feeds.
+type scrapeSummary struct {
+	fetched  int
+	failed   int
+	newPosts int
+}
+
+func (sum *scrapeSummary) add(other scrapeSummary) {
+	sum.fetched += other.fetched
+	sum.failed += other.failed
+	sum.newPosts += other.newPosts
+}
+
+type batchResult struct {
+	summary scrapeSummary
+	err     error
+}
+
+// runAggregator scrapes a batch of due feeds every interval until it
+// receives SIGINT or SIGTERM. On the first of those it stops starting new
+// batches and gives in-flight fetches up to opts.shutdownTimeout to finish
+// before cancelling them; a second signal cancels them at once. SIGHUP
+// reloads the configuration between batches.
+func runAggregator(s *state, opts aggregateOptions, interval time.Duration) error {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	err := serveMetrics(ctx, s, opts)
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var total scrapeSummary
+	passes := 0
+	running := false
+	tickPending := false
+	reloadPending := false
+	stopping := false
+	var deadline <-chan time.Time
+	batchDone := make(chan batchResult, 1)
+
+	startBatch := func() {
+		running = true
+		go func() {
+			summary, err := scrapeFeeds(ctx, s, opts)
+			batchDone <- batchResult{summary: summary, err: err}
+		}()
+	}
+
+	startBatch()
+	for {
+		select {
+		case result := <-batchDone:
+			running = false
+			passes++
+			total.add(result.summary)
+			if result.err != nil {
+				s.logger.Error("pass failed", "error", result.err)
+			}
+			s.logger.Info("pass complete",
+				"fetched", result.summary.fetched,
+				"failed", result.summary.failed,
+				"new_posts", result.summary.newPosts,
+			)
+			if reloadPending {
+				reloadPending = false
+				reloadConfig(s, opts)
+			}
+			if stopping {
+				logAggregatorSummary(s, passes, total)
+				return nil
+			}
+			if tickPending {
+				tickPending = false
+				startBatch()
+			}
+		case <-ticker.C:
+			if stopping {
+				continue
+			}
+			if running {
+				tickPending = true
+				continue
+			}
+			startBatch()
+		case sig := <-signals:
+			switch {
+			case sig == syscall.SIGHUP:
+				if running {
+					reloadPending = true
+				} else {
+					reloadConfig(s, opts)
+				}
+			case stopping:
+				s.logger.Warn("received signal again, cancelling in-flight fetches", "signal", sig)
+				cancel(errAggregatorStopped)
+			case !running:
+				logAggregatorSummary(s, passes, total)
+				return nil
+			default:
+				stopping = true
+				s.logger.Info("received signal, waiting for in-flight fetches to finish", "signal", sig, "timeout", opts.shutdownTimeout)
+				deadline = time.After(opts.shutdownTimeout)
+			}
+		case <-deadline:
+			s.logger.Warn("shutdown timeout reached, cancelling in-flight fetches")
+			cancel(errAggregatorStopped)
+		}
+	}
+}
+
+// runAggregatorOnce fetches every due feed once, in batches, and returns
+// an error if any of them failed. SIGINT or SIGTERM cancels the run.
+func runAggregatorOnce(s *state, opts aggregateOptions) error {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	err := serveMetrics(ctx, s, opts)
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case sig := <-signals:
+			s.logger.Warn("received signal, cancelling in-flight fetches", "signal", sig)
+			cancel(errAggregatorStopped)
+		case <-ctx.Done():
+		}
+	}()
+
+	var total scrapeSummary
+	passes := 0
+	for ctx.Err() == nil {
+		// fetched and failed feeds are rescheduled into the future, so
+		// each pass claims feeds that haven't been tried yet
+		summary, err := scrapeFeeds(ctx, s, opts)
+		if err != nil {
+			return err
+		}
+		if summary.fetched+summary.failed == 0 {
+			break
+		}
+		passes++
+		total.add(summary)
+	}
+	logAggregatorSummary(s, passes, total)
+	if total.failed > 0 {
+		return fmt.Errorf("%d of %d feeds failed", total.failed, total.fetched+total.failed)
+	}
+	return context.Cause(ctx)
+}
+
+// serveMetrics starts the Prometheus listener if one was requested. It
+// stops when ctx is done.
+func serveMetrics(ctx context.Context, s *state, opts aggregateOptions) error {
+	if opts.metricsAddr == "" {
+		return nil
+	}
+	err := metrics.Serve(ctx, opts.metricsAddr)
+	if err != nil {
+		return fmt.Errorf("start metrics listener: %w", err)
+	}
+	s.logger.Info("serving metrics", "addr", opts.metricsAddr, "path", "/metrics")
+	return nil
+}
+
+// reloadConfig re-reads the configuration file. The log level follows the
+// new configuration unless it was set with --log-level; the log format
+// only changes on restart.
+func reloadConfig(s *state, opts aggregateOptions) {
+	cfg, err := config.Read()
+	if err != nil {
+		s.logger.Error("reload configuration", "error", err)
+		return
+	}
+	levelName := cfg.LogLevel
+	if opts.logLevel != "" {
+		levelName = opts.logLevel
+	}
+	level, err := parseLogLevel(levelName)
+	if err != nil {
+		s.logger.Error("reload configuration", "error", err)
+		return
+	}
+	*s.config = cfg
+	s.logLevel.Set(level)
+	s.logger.Info("configuration reloaded")
+}
+
+func logAggregatorSummary(s *state, passes int, total scrapeSummary) {
+	s.logger.Info("aggregator stopped",
+		"passes", passes,
+		"fetched", total.fetched,
+		"failed", total.failed,
+		"new_posts", total.newPosts,
+	)
+}
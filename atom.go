@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type AtomFeed struct {
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Links    []AtomLink  `xml:"link"`
+	Entries  []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Links     []AtomLink   `xml:"link"`
+	Summary   string       `xml:"summary"`
+	Content   AtomContent  `xml:"content"`
+	Updated   string       `xml:"updated"`
+	Published string       `xml:"published"`
+	Authors   []AtomPerson `xml:"author"`
+}
+
+type AtomPerson struct {
+	Name string `xml:"name"`
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type AtomContent struct {
+	Type     string `xml:"type,attr"`
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+// String returns the content body. XHTML content is carried as child
+// elements rather than text, so its markup is returned as-is.
+func (c AtomContent) String() string {
+	if c.Type == "xhtml" {
+		return c.InnerXML
+	}
+	return c.Text
+}
+
+// toRSSFeed maps an Atom document onto the RSS model consumed by addPosts.
+func (f AtomFeed) toRSSFeed() RSSFeed {
+	var feed RSSFeed
+	feed.Channel.Title = f.Title
+	feed.Channel.Link = atomAlternateLink(f.Links)
+	feed.Channel.Description = f.Subtitle
+	for _, entry := range f.Entries {
+		description := entry.Summary
+		if len(strings.TrimSpace(description)) == 0 {
+			description = entry.Content.String()
+		}
+		pubDate := entry.Published
+		if len(strings.TrimSpace(pubDate)) == 0 {
+			pubDate = entry.Updated
+		}
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       entry.Title,
+			Link:        atomEntryLink(entry),
+			Description: description,
+			PubDate:     pubDate,
+			Author:      atomAuthorNames(entry.Authors),
+			GUID:        entry.ID,
+			Content:     entry.Content.String(),
+			Enclosures:  atomEnclosures(entry.Links),
+		})
+	}
+	return feed
+}
+
+func atomAuthorNames(authors []AtomPerson) string {
+	names := make([]string, 0, len(authors))
+	for _, author := range authors {
+		name := strings.TrimSpace(author.Name)
+		if len(name) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// atomAlternateLink returns the rel="alternate" link, which is also the
+// meaning of a link with no rel attribute. Other links point at the feed
+// itself, an editing API or media, so none is returned without one.
+func atomAlternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// atomEntryLink returns an entry's alternate link, or failing that its
+// ID when the ID is a web page URL. An entry with neither has no link and
+// is skipped.
+func atomEntryLink(entry AtomEntry) string {
+	link := atomAlternateLink(entry.Links)
+	if len(link) != 0 {
+		return link
+	}
+	id := strings.TrimSpace(entry.ID)
+	parsed, err := url.Parse(id)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+		return ""
+	}
+	return id
+}
+
+func atomEnclosures(links []AtomLink) []RSSEnclosure {
+	var enclosures []RSSEnclosure
+	for _, link := range links {
+		if link.Rel == "enclosure" {
+			enclosures = append(enclosures, RSSEnclosure{
+				URL:    link.Href,
+				Type:   link.Type,
+				Length: link.Length,
+			})
+		}
+	}
+	return enclosures
+}
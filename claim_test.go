@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/a-fleming/gator/internal/database"
+)
+
+// testDatabaseURLEnv names a database the integration tests may wipe, in
+// the same form as db_url. They are skipped when it isn't set.
+const testDatabaseURLEnv = "GATOR_TEST_DATABASE_URL"
+
+func openTestStorage(t *testing.T) database.Storage {
+	t.Helper()
+	dbURL := os.Getenv(testDatabaseURLEnv)
+	if len(dbURL) == 0 {
+		t.Skipf("%s is not set", testDatabaseURLEnv)
+	}
+	storage, migrations, err := openStorage(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_, err = migrations.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = storage.Reset(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		storage.Reset(context.Background())
+	})
+	return storage
+}
+
+func TestClaimFeedsToFetchConcurrently(t *testing.T) {
+	storage := openTestStorage(t)
+	ctx := context.Background()
+	const feedCount = 200
+	const claimers = 8
+
+	user, err := storage.CreateUser(ctx, "claimer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx := range feedCount {
+		_, err := storage.CreateFeed(ctx, database.CreateFeedParams{
+			Name:   fmt.Sprintf("feed %d", idx),
+			Url:    fmt.Sprintf("https://example.com/%d.xml", idx),
+			UserID: user.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	claimedBy := map[string]int{}
+	var wg sync.WaitGroup
+	errs := make(chan error, claimers)
+	for claimer := range claimers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				feeds, err := storage.ClaimFeedsToFetch(ctx, database.ClaimFeedsToFetchParams{
+					LeaseSeconds: 600,
+					BatchSize:    7,
+				})
+				if err != nil {
+					errs <- err
+					return
+				}
+				if len(feeds) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, feed := range feeds {
+					if previous, ok := claimedBy[feed.ID.String()]; ok {
+						t.Errorf("feed %s claimed by claimers %d and %d", feed.Name, previous, claimer)
+					}
+					claimedBy[feed.ID.String()] = claimer
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if len(claimedBy) != feedCount {
+		t.Errorf("%d of %d feeds were claimed", len(claimedBy), feedCount)
+	}
+}
@@ -2,18 +2,37 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/a-fleming/gator/internal/config"
 	"github.com/a-fleming/gator/internal/database"
+	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
 )
 
 type state struct {
-	config *config.Config
-	db     *database.Queries
+	config   *config.Config
+	db       database.Storage
+	logger   *slog.Logger
+	logLevel *slog.LevelVar
+	// migrations applies the schema migrations built into the binary
+	migrations *goose.Provider
+	// fetcher fetches feeds over HTTP, or from memory in tests
+	fetcher feedFetcher
+	stdin   io.Reader
+	stdout  io.Writer
 }
 
 type command struct {
@@ -32,13 +51,22 @@ func GetCommands() commands {
 	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
 	cmds.register("agg", handlerAggregate)
 	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
+	cmds.register("download", handlerDownload)
+	cmds.register("enable-feed", handlerEnableFeed)
+	cmds.register("export", middlewareLoggedIn(handlerExport))
+	cmds.register("feed-log", handlerFeedLog)
 	cmds.register("feeds", handlerFeeds)
 	cmds.register("follow", middlewareLoggedIn(handlerFollow))
 	cmds.register("following", middlewareLoggedIn(handlerFollowing))
+	cmds.register("import", middlewareLoggedIn(handlerImport))
 	cmds.register("login", handlerLogin)
 	cmds.register("logout", handlerLogout)
+	cmds.register("migrate", handlerMigrate)
+	cmds.register("read", handlerRead)
+	cmds.register("refresh", handlerRefresh)
 	cmds.register("register", handlerRegister)
 	cmds.register("reset", handlerReset)
+	cmds.register("set-interval", handlerSetInterval)
 	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
 	cmds.register("users", handlerUsers)
 	return cmds
@@ -47,22 +75,47 @@ func GetCommands() commands {
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
 	return func(s *state, cmd command) error {
 		if s.config.CurrentUserID == "" {
-			return fmt.Errorf("you must be logged in to run '%s'", cmd.name)
+			return newCommandError(ErrNotLoggedIn, "you must be logged in to run '%s'", cmd.name)
 		}
 		user, err := s.db.GetUser(context.Background(), s.config.CurrentUserName)
 		if err != nil {
+			if errors.Is(classifyDBError(err), ErrNotFound) {
+				return newCommandError(ErrNotLoggedIn, "logged in user '%s' no longer exists; log in again to run '%s'", s.config.CurrentUserName, cmd.name)
+			}
 			return err
 		}
 		return handler(s, cmd, user)
 	}
 }
 
+// getFeedByURL looks up a feed, reporting a missing one as ErrNotFound.
+func getFeedByURL(ctx context.Context, s *state, feedURL string) (database.Feed, error) {
+	feed, err := s.db.GetFeedByUrl(ctx, feedURL)
+	if errors.Is(classifyDBError(err), ErrNotFound) {
+		return feed, newCommandError(ErrNotFound, "feed not found at '%s'", feedURL)
+	}
+	return feed, err
+}
+
+// getPost looks up a post, reporting a missing one as ErrNotFound.
+func getPost(ctx context.Context, s *state, postID uuid.UUID) (database.Post, error) {
+	post, err := s.db.GetPost(ctx, postID)
+	if errors.Is(classifyDBError(err), ErrNotFound) {
+		return post, newCommandError(ErrNotFound, "post not found with ID '%s'", postID)
+	}
+	return post, err
+}
+
 func handlerAddFeed(s *state, cmd command, user database.User) error {
 	if len(cmd.arguments) < 2 {
 		return fmt.Errorf("gator addfeed: error: the following arguments are required: name url")
 	}
 	feedName := cmd.arguments[0]
-	feedURL := cmd.arguments[1]
+	// fetching the feed validates it before anything is written
+	feedURL, err := discoverFeedURL(s, cmd.arguments[1])
+	if err != nil {
+		return err
+	}
 
 	createFeedParams := database.CreateFeedParams{
 		Name:   feedName,
@@ -70,61 +123,141 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 		UserID: user.ID,
 	}
 
+	// the feed is only added if the user can also follow it
+	var feedInfo database.Feed
+	var followedFeed database.CreateFeedFollowRow
 	ctx := context.Background()
-	feedInfo, err := s.db.CreateFeed(ctx, createFeedParams)
+	err = s.db.ExecTx(ctx, func(q database.Querier) error {
+		var err error
+		feedInfo, err = q.CreateFeed(ctx, createFeedParams)
+		if err != nil {
+			if errors.Is(classifyDBError(err), ErrAlreadyExists) {
+				return newCommandError(ErrAlreadyExists, "a feed named '%s' or at '%s' has already been added", feedName, feedURL)
+			}
+			return err
+		}
+		createFeedFollowParams := database.CreateFeedFollowParams{
+			UserID: user.ID,
+			FeedID: feedInfo.ID,
+		}
+		followedFeed, err = q.CreateFeedFollow(ctx, createFeedFollowParams)
+		return err
+	})
 	if err != nil {
 		return err
 	}
-	fmt.Println("successfully added RSS feed")
-	fmt.Printf("id: %+v\n", feedInfo.ID)
-	fmt.Printf("name: %s\n", feedName)
-	fmt.Printf("url: %s\n", feedURL)
-	fmt.Printf("createAt: %v\n", feedInfo.CreatedAt)
-	fmt.Printf("updatedAt: %v\n", feedInfo.UpdatedAt)
-	fmt.Printf("user_id: %+v\n", feedInfo.ID)
+	fmt.Fprintln(s.stdout, "successfully added RSS feed")
+	fmt.Fprintf(s.stdout, "id: %+v\n", feedInfo.ID)
+	fmt.Fprintf(s.stdout, "name: %s\n", feedName)
+	fmt.Fprintf(s.stdout, "url: %s\n", feedURL)
+	fmt.Fprintf(s.stdout, "createAt: %v\n", feedInfo.CreatedAt)
+	fmt.Fprintf(s.stdout, "updatedAt: %v\n", feedInfo.UpdatedAt)
+	fmt.Fprintf(s.stdout, "user_id: %+v\n", feedInfo.UserID)
+	fmt.Fprintf(s.stdout, "'%s' has followed '%s'\n", followedFeed.UserName, followedFeed.FeedName)
+	return nil
+}
 
-	createFeedFollowParams := database.CreateFeedFollowParams{
-		UserID: user.ID,
-		FeedID: feedInfo.ID,
+// discoverFeedURL validates pageURL as a feed, or finds the feeds linked
+// from it when it is an HTML page and asks the user to pick one.
+func discoverFeedURL(s *state, pageURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	httpTimeoutSec := 15
+	parsed, err := url.Parse(pageURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+		return "", fmt.Errorf("'%s': %w", pageURL, errNotAPage)
+	}
+	candidates, err := s.fetcher.DiscoverFeeds(ctx, pageURL, httpTimeoutSec)
+	if err != nil {
+		return "", fmt.Errorf("fetch '%s': %w", pageURL, err)
 	}
-	followedFeed, err := s.db.CreateFeedFollow(ctx, createFeedFollowParams)
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w at '%s'", errNoFeedFound, pageURL)
+	}
+	chosen, err := chooseFeedCandidate(candidates, s.stdin, s.stdout)
 	if err != nil {
-		return err
+		return "", err
 	}
-	fmt.Printf("'%s' has followed '%s'\n", followedFeed.UserName, followedFeed.FeedName)
-	return nil
+	if chosen.URL != pageURL {
+		fmt.Fprintf(s.stdout, "using feed at '%s'\n", chosen.URL)
+	}
+	return chosen.URL, nil
 }
 
 func handlerAggregate(s *state, cmd command) error {
-	if len(cmd.arguments) == 0 {
+	flags := flag.NewFlagSet("agg", flag.ContinueOnError)
+	concurrency := flags.Int("concurrency", 1, "number of feeds fetched in parallel")
+	batchSize := flags.Int("batch", 0, "number of feeds fetched per interval (default: concurrency)")
+	feedTimeout := flags.Duration("timeout", time.Minute, "time limit for fetching and storing one feed")
+	shutdownTimeout := flags.Duration("shutdown-timeout", 10*time.Second, "time allowed for in-flight fetches to finish on shutdown")
+	once := flags.Bool("once", false, "fetch every due feed once, then exit")
+	metricsAddr := flags.String("metrics-addr", "", "serve Prometheus metrics on this address, e.g. :9090")
+	logLevel := flags.String("log-level", "", "log level: debug, info, warn or error (default: log_level from config, or info)")
+	logFormat := flags.String("log-format", "", "log format: text or json (default: log_format from config, or text)")
+	args, err := parseFlags(flags, cmd.arguments)
+	if err != nil {
+		return fmt.Errorf("gator agg: error: %w", err)
+	}
+	if len(args) == 0 && !*once {
 		return fmt.Errorf("gator agg: error: the following argument is required: time_between_requests")
 	}
-	timeStr := cmd.arguments[0]
-	timeBetweenRequests, err := time.ParseDuration(timeStr)
-	if err != nil {
-		return fmt.Errorf("invalid interval %q (expected values like 30s, 5m, 1h)", timeStr)
+	if *concurrency < 1 {
+		return fmt.Errorf("gator agg: error: --concurrency must be at least 1")
 	}
-	fmt.Printf("collecting feeds every %s\n", timeStr)
-	ticker := time.NewTicker(timeBetweenRequests)
-	ctx := context.Background()
-	for ; ; <-ticker.C {
-		httpTimeoutSec := 15
-		err = scrapeFeeds(ctx, s, httpTimeoutSec)
+	if *batchSize == 0 {
+		*batchSize = *concurrency
+	}
+	if *batchSize < 1 {
+		return fmt.Errorf("gator agg: error: --batch must be at least 1")
+	}
+	if *logLevel != "" {
+		level, err := parseLogLevel(*logLevel)
 		if err != nil {
-			return err
+			return fmt.Errorf("gator agg: error: %w", err)
+		}
+		s.logLevel.Set(level)
+	}
+	if *logFormat != "" {
+		logger, err := newLogger(os.Stderr, *logFormat, s.logLevel)
+		if err != nil {
+			return fmt.Errorf("gator agg: error: %w", err)
 		}
-		fmt.Println()
-		fmt.Println()
-		fmt.Println("----------------------------------------------------------")
-		fmt.Println()
-		fmt.Println()
+		s.logger = logger
+	}
+	opts := aggregateOptions{
+		concurrency:     *concurrency,
+		batchSize:       *batchSize,
+		feedTimeout:     *feedTimeout,
+		httpTimeoutSec:  15,
+		shutdownTimeout: *shutdownTimeout,
+		metricsAddr:     *metricsAddr,
+		logLevel:        *logLevel,
+	}
+
+	if *once {
+		s.logger.Info("collecting all due feeds", "concurrency", opts.concurrency)
+		return runAggregatorOnce(s, opts)
 	}
+
+	timeStr := args[0]
+	timeBetweenRequests, err := time.ParseDuration(timeStr)
+	if err != nil {
+		return fmt.Errorf("invalid interval %q (expected values like 30s, 5m, 1h)", timeStr)
+	}
+	s.logger.Info("collecting feeds", "interval", timeBetweenRequests, "batch", opts.batchSize, "concurrency", opts.concurrency)
+	return runAggregator(s, opts, timeBetweenRequests)
 }
 
 func handlerBrowse(s *state, cmd command, user database.User) error {
+	flags := flag.NewFlagSet("browse", flag.ContinueOnError)
+	full := flags.Bool("full", false, "show each post's full content instead of its description")
+	args, err := parseFlags(flags, cmd.arguments)
+	if err != nil {
+		return fmt.Errorf("gator browse: error: %w", err)
+	}
 	limit := int32(2)
-	if len(cmd.arguments) > 0 {
-		parsedLimit, err := strconv.ParseInt(cmd.arguments[0], 10, 32)
+	if len(args) > 0 {
+		parsedLimit, err := strconv.ParseInt(args[0], 10, 32)
 		if err != nil {
 			return err
 		}
@@ -145,11 +278,164 @@ func handlerBrowse(s *state, cmd command, user database.User) error {
 			descriptionStr = post.Description.String
 		}
 
-		fmt.Printf("%d. Title: %s\n", idx+1, post.Title)
-		fmt.Printf("-- Link: %s\n", post.Url)
-		fmt.Printf("-- Date: %v\n", post.PublishedAt)
-		fmt.Printf("-- Description: %s\n", descriptionStr)
-		fmt.Println()
+		fmt.Fprintf(s.stdout, "%d. Title: %s\n", idx+1, post.Title)
+		fmt.Fprintf(s.stdout, "-- ID: %s\n", post.PostID)
+		fmt.Fprintf(s.stdout, "-- Link: %s\n", post.Url)
+		fmt.Fprintf(s.stdout, "-- Date: %v\n", post.PublishedAt)
+		if post.Author.Valid {
+			fmt.Fprintf(s.stdout, "-- Author: %s\n", post.Author.String)
+		}
+		enclosures, err := s.db.GetEnclosuresForPost(ctx, post.PostID)
+		if err != nil {
+			return err
+		}
+		for _, enclosure := range enclosures {
+			fmt.Fprintf(s.stdout, "-- Enclosure: %s\n", describeEnclosure(enclosure))
+		}
+		if *full && post.Content.Valid {
+			fmt.Fprintf(s.stdout, "-- Content:\n%s\n", htmlToText(post.Content.String))
+		} else {
+			fmt.Fprintf(s.stdout, "-- Description: %s\n", descriptionStr)
+		}
+		fmt.Fprintln(s.stdout)
+	}
+	return nil
+}
+
+func handlerDownload(s *state, cmd command) error {
+	flags := flag.NewFlagSet("download", flag.ContinueOnError)
+	dir := flags.String("dir", "", "directory to save to (default: download_dir from config, or ~/gator-downloads)")
+	args, err := parseFlags(flags, cmd.arguments)
+	if err != nil {
+		return fmt.Errorf("gator download: error: %w", err)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("gator download: error: the following argument is required: post")
+	}
+	postID, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("gator download: error: argument post: invalid post ID '%s'", args[0])
+	}
+	downloadDir := *dir
+	if downloadDir == "" {
+		downloadDir, err = s.config.DownloadDirectory()
+		if err != nil {
+			return err
+		}
+	}
+
+	// an interrupted download keeps its partial file and resumes next time
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	post, err := getPost(ctx, s, postID)
+	if err != nil {
+		return err
+	}
+	enclosures, err := s.db.GetEnclosuresForPost(ctx, post.ID)
+	if err != nil {
+		return err
+	}
+	if len(enclosures) == 0 {
+		return fmt.Errorf("post '%s' has no enclosures", post.Title)
+	}
+
+	for _, enclosure := range enclosures {
+		if enclosure.DownloadPath.Valid {
+			_, err := os.Stat(enclosure.DownloadPath.String)
+			if err == nil {
+				fmt.Fprintf(s.stdout, "already downloaded: %s (sha256 %s)\n", enclosure.DownloadPath.String, enclosure.Sha256.String)
+				continue
+			}
+		}
+		result, err := downloadEnclosure(ctx, enclosure, downloadDir)
+		if err != nil {
+			return fmt.Errorf("download '%s': %w", enclosure.Url, err)
+		}
+		params := database.MarkEnclosureDownloadedParams{
+			DownloadPath: nullString(result.Path),
+			Sha256:       nullString(result.Sha256),
+			ID:           enclosure.ID,
+		}
+		err = s.db.MarkEnclosureDownloaded(ctx, params)
+		if err != nil {
+			return err
+		}
+		verb := "saved"
+		if result.Resumed {
+			verb = "resumed and saved"
+		}
+		fmt.Fprintf(s.stdout, "%s %s (%d bytes, sha256 %s)\n", verb, result.Path, result.Bytes, result.Sha256)
+	}
+	return nil
+}
+
+func handlerEnableFeed(s *state, cmd command) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf("gator enable-feed: error: the following argument is required: url")
+	}
+	feedURL := cmd.arguments[0]
+
+	ctx := context.Background()
+	feed, err := getFeedByURL(ctx, s, feedURL)
+	if err != nil {
+		return err
+	}
+	err = s.db.EnableFeed(ctx, feed.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.stdout, "'%s' has been enabled and will be fetched on the next pass\n", feed.Name)
+	return nil
+}
+
+func handlerFeedLog(s *state, cmd command) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf("gator feed-log: error: the following argument is required: url")
+	}
+	feedURL := cmd.arguments[0]
+	limit := int32(10)
+	if len(cmd.arguments) > 1 {
+		parsedLimit, err := strconv.ParseInt(cmd.arguments[1], 10, 32)
+		if err != nil {
+			return err
+		}
+		limit = int32(parsedLimit)
+	}
+
+	ctx := context.Background()
+	feed, err := getFeedByURL(ctx, s, feedURL)
+	if err != nil {
+		return err
+	}
+	params := database.GetFeedFetchesForFeedParams{
+		FeedID: feed.ID,
+		Limit:  limit,
+	}
+	fetches, err := s.db.GetFeedFetchesForFeed(ctx, params)
+	if err != nil {
+		return err
+	}
+	if len(fetches) == 0 {
+		fmt.Fprintf(s.stdout, "'%s' has not been fetched yet\n", feed.Name)
+		return nil
+	}
+
+	fmt.Fprintf(s.stdout, "recent fetches of '%s':\n", feed.Name)
+	for _, fetch := range fetches {
+		statusStr := "no response"
+		if fetch.StatusCode.Valid {
+			statusStr = fmt.Sprintf("%d %s", fetch.StatusCode.Int32, http.StatusText(int(fetch.StatusCode.Int32)))
+		}
+		fmt.Fprintf(s.stdout, "* %v\n", fetch.CreatedAt)
+		fmt.Fprintf(s.stdout, "--- status: %s\n", statusStr)
+		fmt.Fprintf(s.stdout, "--- duration: %v\n", time.Duration(fetch.DurationMs)*time.Millisecond)
+		fmt.Fprintf(s.stdout, "--- new posts: %d\n", fetch.NewPosts)
+		if fetch.SkippedItems > 0 {
+			fmt.Fprintf(s.stdout, "--- skipped items: %d\n", fetch.SkippedItems)
+		}
+		if fetch.Error.Valid {
+			fmt.Fprintf(s.stdout, "--- error: %s\n", fetch.Error.String)
+		}
 	}
 	return nil
 }
@@ -165,13 +451,56 @@ func handlerFeeds(s *state, cmd command) error {
 		if err != nil {
 			return err
 		}
-		fmt.Printf("* %s\n", feed.Name)
-		fmt.Printf("--- url: %s\n", feed.Url)
-		fmt.Printf("--- added by: %s\n", user.Name)
+		fmt.Fprintf(s.stdout, "* %s\n", feed.Name)
+		fmt.Fprintf(s.stdout, "--- url: %s\n", feed.Url)
+		fmt.Fprintf(s.stdout, "--- added by: %s\n", user.Name)
+		intervalStr := (time.Duration(feed.FetchIntervalSeconds) * time.Second).String()
+		if feed.AdaptiveInterval {
+			intervalStr += " (adaptive)"
+		}
+		fmt.Fprintf(s.stdout, "--- fetch interval: %s\n", intervalStr)
+		fmt.Fprintf(s.stdout, "--- next fetch: %v\n", feed.NextFetchAt)
+		fmt.Fprintf(s.stdout, "--- health: %s\n", feedHealth(feed))
 	}
 	return nil
 }
 
+func describeEnclosure(enclosure database.Enclosure) string {
+	var details []string
+	if enclosure.MimeType.Valid {
+		details = append(details, enclosure.MimeType.String)
+	}
+	if enclosure.LengthBytes.Valid {
+		details = append(details, fmt.Sprintf("%d bytes", enclosure.LengthBytes.Int64))
+	}
+	if enclosure.DurationSeconds.Valid {
+		details = append(details, (time.Duration(enclosure.DurationSeconds.Int32) * time.Second).String())
+	}
+	if enclosure.DownloadPath.Valid {
+		details = append(details, "downloaded to "+enclosure.DownloadPath.String)
+	}
+	if len(details) == 0 {
+		return enclosure.Url
+	}
+	return fmt.Sprintf("%s (%s)", enclosure.Url, strings.Join(details, ", "))
+}
+
+func feedHealth(feed database.Feed) string {
+	var health string
+	switch {
+	case feed.DisabledAt.Valid:
+		health = fmt.Sprintf("disabled since %v after %d consecutive failures", feed.DisabledAt.Time, feed.ConsecutiveFailures)
+	case feed.ConsecutiveFailures > 0:
+		health = fmt.Sprintf("failing (%d consecutive failures)", feed.ConsecutiveFailures)
+	default:
+		return "ok"
+	}
+	if feed.LastFetchError.Valid {
+		health += fmt.Sprintf(", last error: %s", feed.LastFetchError.String)
+	}
+	return health
+}
+
 func handlerFollow(s *state, cmd command, user database.User) error {
 	if len(cmd.arguments) == 0 {
 		return fmt.Errorf("gator follow: error: the following argument is required: url")
@@ -179,12 +508,26 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 	feedURL := cmd.arguments[0]
 
 	ctx := context.Background()
-	feedInfo, err := s.db.GetFeedByUrl(ctx, feedURL)
+	feedInfo, err := getFeedByURL(ctx, s, feedURL)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			return fmt.Errorf("feed not found at '%s'", feedURL)
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		// the URL may be a site's homepage rather than one of its feeds
+		discoveredURL, discoverErr := discoverFeedURL(s, feedURL)
+		if discoverErr != nil && !isNoFeedError(discoverErr) {
+			return fmt.Errorf("feed not found at '%s', and looking for its feeds failed: %w", feedURL, discoverErr)
+		}
+		if discoverErr != nil || discoveredURL == feedURL {
+			return err
+		}
+		feedInfo, err = getFeedByURL(ctx, s, discoveredURL)
+		if errors.Is(err, ErrNotFound) {
+			return newCommandError(ErrNotFound, "feed not found at '%s'; add it with: gator addfeed <name> %s", feedURL, discoveredURL)
+		}
+		if err != nil {
+			return err
 		}
-		return err
 	}
 
 	params := database.CreateFeedFollowParams{
@@ -193,9 +536,12 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 	}
 	followedFeed, err := s.db.CreateFeedFollow(ctx, params)
 	if err != nil {
+		if errors.Is(classifyDBError(err), ErrAlreadyFollowing) {
+			return newCommandError(ErrAlreadyFollowing, "'%s' is already following '%s'", user.Name, feedInfo.Name)
+		}
 		return err
 	}
-	fmt.Printf("'%s' has followed '%s'\n", followedFeed.UserName, followedFeed.FeedName)
+	fmt.Fprintf(s.stdout, "'%s' has followed '%s'\n", followedFeed.UserName, followedFeed.FeedName)
 	return nil
 }
 
@@ -207,13 +553,17 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 		return err
 	}
 	if len(feedFollows) == 0 {
-		fmt.Printf("'%s' is not following any feeds\n", s.config.CurrentUserName)
+		fmt.Fprintf(s.stdout, "'%s' is not following any feeds\n", s.config.CurrentUserName)
 		return nil
 	}
 
-	fmt.Printf("'%s' is following:\n", s.config.CurrentUserName)
+	fmt.Fprintf(s.stdout, "'%s' is following:\n", s.config.CurrentUserName)
 	for _, feedFollow := range feedFollows {
-		fmt.Printf("* '%s' (%s)\n", feedFollow.FeedName, feedFollow.FeedUrl)
+		if feedFollow.Folder.Valid {
+			fmt.Fprintf(s.stdout, "* '%s' (%s) in %s\n", feedFollow.FeedName, feedFollow.FeedUrl, feedFollow.Folder.String)
+			continue
+		}
+		fmt.Fprintf(s.stdout, "* '%s' (%s)\n", feedFollow.FeedName, feedFollow.FeedUrl)
 	}
 	return nil
 }
@@ -226,17 +576,17 @@ func handlerLogin(s *state, cmd command) error {
 
 	ctx := context.Background()
 	user, err := s.db.GetUser(ctx, userName)
-	if user == (database.User{}) {
-		return fmt.Errorf("username '%s' does not exist in database", userName)
-	}
 	if err != nil {
+		if errors.Is(classifyDBError(err), ErrNotFound) {
+			return newCommandError(ErrNotFound, "username '%s' does not exist in database", userName)
+		}
 		return err
 	}
 	err = s.config.SetUser(userName, user.ID.String())
 	if err != nil {
 		return err
 	}
-	fmt.Printf("username '%s' has been set\n", userName)
+	fmt.Fprintf(s.stdout, "username '%s' has been set\n", userName)
 	return nil
 }
 
@@ -246,7 +596,63 @@ func handlerLogout(s *state, cmd command) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("'%s' was logged out\n", loggedOutUserName)
+	fmt.Fprintf(s.stdout, "'%s' was logged out\n", loggedOutUserName)
+	return nil
+}
+
+func handlerRead(s *state, cmd command) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf("gator read: error: the following argument is required: post")
+	}
+	postID, err := uuid.Parse(cmd.arguments[0])
+	if err != nil {
+		return fmt.Errorf("gator read: error: argument post: invalid post ID '%s'", cmd.arguments[0])
+	}
+
+	post, err := getPost(context.Background(), s, postID)
+	if err != nil {
+		return err
+	}
+	// fall back to the description for feeds that don't carry full content
+	body := post.Content
+	if !body.Valid {
+		body = post.Description
+	}
+
+	fmt.Fprintf(s.stdout, "%s\n", post.Title)
+	fmt.Fprintf(s.stdout, "-- Link: %s\n", post.Url)
+	fmt.Fprintf(s.stdout, "-- Date: %v\n", post.PublishedAt)
+	if post.Author.Valid {
+		fmt.Fprintf(s.stdout, "-- Author: %s\n", post.Author.String)
+	}
+	fmt.Fprintln(s.stdout)
+	fmt.Fprintln(s.stdout, htmlToText(body.String))
+	return nil
+}
+
+func handlerRefresh(s *state, cmd command) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf("gator refresh: error: the following argument is required: url")
+	}
+	feedURL := cmd.arguments[0]
+
+	ctx := context.Background()
+	feed, err := getFeedByURL(ctx, s, feedURL)
+	if err != nil {
+		return err
+	}
+	if feed.DisabledAt.Valid {
+		fmt.Fprintf(s.stdout, "'%s' has been %s\n", feed.Name, feedHealth(feed))
+		fmt.Fprintf(s.stdout, "the aggregator skips it until it is re-enabled with 'gator enable-feed %s'\n", feed.Url)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	httpTimeoutSec := 15
+	newPosts, err := scrapeFeed(ctx, s, feed, httpTimeoutSec)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.stdout, "refreshed '%s': %d new posts\n", feed.Name, newPosts)
 	return nil
 }
 
@@ -259,6 +665,9 @@ func handlerRegister(s *state, cmd command) error {
 	ctx := context.Background()
 	user, err := s.db.CreateUser(ctx, userName)
 	if err != nil {
+		if errors.Is(classifyDBError(err), ErrAlreadyExists) {
+			return newCommandError(ErrAlreadyExists, "username '%s' is already taken", userName)
+		}
 		return err
 	}
 
@@ -266,8 +675,8 @@ func handlerRegister(s *state, cmd command) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("user '%s' was created\n", userName)
-	fmt.Printf("%+v\n", user)
+	fmt.Fprintf(s.stdout, "user '%s' was created\n", userName)
+	fmt.Fprintf(s.stdout, "%+v\n", user)
 	return nil
 }
 
@@ -281,7 +690,49 @@ func handlerReset(s *state, cmd command) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println("successfully reset 'users' table")
+	fmt.Fprintln(s.stdout, "successfully reset 'users' table")
+	return nil
+}
+
+func handlerSetInterval(s *state, cmd command) error {
+	flags := flag.NewFlagSet("set-interval", flag.ContinueOnError)
+	adaptive := flags.Bool("adaptive", false, "adjust the interval to how often the feed publishes")
+	args, err := parseFlags(flags, cmd.arguments)
+	if err != nil {
+		return fmt.Errorf("gator set-interval: error: %w", err)
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("gator set-interval: error: the following arguments are required: url interval")
+	}
+	feedURL := args[0]
+	intervalStr := args[1]
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil {
+		return fmt.Errorf("invalid interval %q (expected values like 30m, 1h, 6h)", intervalStr)
+	}
+	if interval < minFetchInterval || interval > maxFetchInterval {
+		return fmt.Errorf("interval must be between %v and %v", minFetchInterval, maxFetchInterval)
+	}
+
+	ctx := context.Background()
+	feed, err := getFeedByURL(ctx, s, feedURL)
+	if err != nil {
+		return err
+	}
+	params := database.SetFeedFetchIntervalParams{
+		FetchIntervalSeconds: int32(interval.Seconds()),
+		AdaptiveInterval:     *adaptive,
+		ID:                   feed.ID,
+	}
+	err = s.db.SetFeedFetchInterval(ctx, params)
+	if err != nil {
+		return err
+	}
+	if *adaptive {
+		fmt.Fprintf(s.stdout, "'%s' will be fetched adaptively, starting every %v\n", feed.Name, interval)
+	} else {
+		fmt.Fprintf(s.stdout, "'%s' will be fetched every %v\n", feed.Name, interval)
+	}
 	return nil
 }
 
@@ -291,7 +742,7 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 	}
 	feedURL := cmd.arguments[0]
 	ctx := context.Background()
-	feed, err := s.db.GetFeedByUrl(ctx, feedURL)
+	feed, err := getFeedByURL(ctx, s, feedURL)
 	if err != nil {
 		return err
 	}
@@ -299,11 +750,14 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 		UserID: user.ID,
 		FeedID: feed.ID,
 	}
-	err = s.db.RemoveFeedFollow(ctx, params)
+	removed, err := s.db.RemoveFeedFollow(ctx, params)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("successfully unfollowed feed '%s'\n", feed.Name)
+	if removed == 0 {
+		return newCommandError(ErrNotFound, "'%s' is not following '%s'", user.Name, feed.Name)
+	}
+	fmt.Fprintf(s.stdout, "successfully unfollowed feed '%s'\n", feed.Name)
 	return nil
 }
 
@@ -315,14 +769,32 @@ func handlerUsers(s *state, cmd command) error {
 	}
 	for _, user := range users {
 		if user.Name == s.config.CurrentUserName {
-			fmt.Printf("* %s (current)\n", user.Name)
+			fmt.Fprintf(s.stdout, "* %s (current)\n", user.Name)
 		} else {
-			fmt.Printf("* %s\n", user.Name)
+			fmt.Fprintf(s.stdout, "* %s\n", user.Name)
 		}
 	}
 	return nil
 }
 
+// parseFlags parses flags that may appear before, after or between a
+// command's positional arguments, and returns the positional arguments.
+func parseFlags(flags *flag.FlagSet, arguments []string) ([]string, error) {
+	flags.SetOutput(io.Discard)
+	var positional []string
+	for {
+		err := flags.Parse(arguments)
+		if err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		arguments = flags.Args()[1:]
+	}
+}
+
 func (c *commands) register(name string, f func(*state, command) error) {
 	c.cliCommands[name] = f
 }
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-fleming/gator/internal/config"
+	"github.com/a-fleming/gator/internal/database"
+	"github.com/a-fleming/gator/internal/memstore"
+)
+
+// fakeFetcher serves feeds and HTML pages from memory.
+type fakeFetcher struct {
+	feeds map[string]RSSFeed
+	// pages maps a page URL to the feed URLs it links to
+	pages map[string][]string
+	// errs maps a page URL to the error discovering its feeds fails with
+	errs map[string]error
+}
+
+func (f fakeFetcher) FetchFeed(ctx context.Context, feedURL string, timeoutSec int, etag string, lastModified string) (fetchResult, error) {
+	feed, ok := f.feeds[feedURL]
+	if !ok {
+		return fetchResult{StatusCode: 404}, fmt.Errorf("unexpected HTTP status 404 Not Found")
+	}
+	return fetchResult{StatusCode: 200, Feed: &feed}, nil
+}
+
+func (f fakeFetcher) DiscoverFeeds(ctx context.Context, pageURL string, timeoutSec int) ([]feedCandidate, error) {
+	if feed, ok := f.feeds[pageURL]; ok {
+		return []feedCandidate{{URL: pageURL, Title: feed.Channel.Title}}, nil
+	}
+	if err, ok := f.errs[pageURL]; ok {
+		return nil, err
+	}
+	links, ok := f.pages[pageURL]
+	if !ok {
+		return nil, httpStatusError{status: "404 Not Found"}
+	}
+	var candidates []feedCandidate
+	for _, link := range links {
+		if feed, ok := f.feeds[link]; ok {
+			candidates = append(candidates, feedCandidate{URL: link, Title: feed.Channel.Title})
+		}
+	}
+	return candidates, nil
+}
+
+func testFeed(title string, items ...RSSItem) RSSFeed {
+	var feed RSSFeed
+	feed.Channel.Title = title
+	feed.Channel.Item = items
+	return feed
+}
+
+// newTestState returns a state on an empty memstore, reading stdin and
+// writing to the returned buffer. The config file is written to a
+// temporary home directory.
+func newTestState(t *testing.T, fetcher feedFetcher, stdin string) (*state, *memstore.Store, *bytes.Buffer) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	store := memstore.New()
+	var stdout bytes.Buffer
+	s := &state{
+		config:  &config.Config{},
+		db:      store,
+		logger:  slog.New(slog.DiscardHandler),
+		fetcher: fetcher,
+		stdin:   strings.NewReader(stdin),
+		stdout:  &stdout,
+	}
+	return s, store, &stdout
+}
+
+// loginTestUser registers name and logs in as them.
+func loginTestUser(t *testing.T, s *state, name string) database.User {
+	t.Helper()
+	err := handlerRegister(s, command{name: "register", arguments: []string{name}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.db.GetUser(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func assertFollowing(t *testing.T, store *memstore.Store, user database.User, wantURLs ...string) {
+	t.Helper()
+	follows, err := store.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, follow := range follows {
+		got = append(got, follow.FeedUrl)
+	}
+	if strings.Join(got, " ") != strings.Join(wantURLs, " ") {
+		t.Errorf("following %v, want %v", got, wantURLs)
+	}
+}
+
+func TestHandlerRegisterAndLogin(t *testing.T) {
+	s, _, stdout := newTestState(t, fakeFetcher{}, "")
+	loginTestUser(t, s, "alice")
+	loginTestUser(t, s, "bob")
+
+	err := handlerRegister(s, command{name: "register", arguments: []string{"alice"}})
+	if !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("registering alice twice: got %v, want ErrAlreadyExists", err)
+	}
+	err = handlerLogin(s, command{name: "login", arguments: []string{"alice"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := config.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.CurrentUserName != "alice" {
+		t.Errorf("config has user %q, want alice", saved.CurrentUserName)
+	}
+	err = handlerLogin(s, command{name: "login", arguments: []string{"carol"}})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("logging in as carol: got %v, want ErrNotFound", err)
+	}
+
+	stdout.Reset()
+	err = handlerUsers(s, command{name: "users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "* alice (current)\n* bob\n"
+	if stdout.String() != want {
+		t.Errorf("users printed %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestHandlerAddFeed(t *testing.T) {
+	fetcher := fakeFetcher{feeds: map[string]RSSFeed{
+		"https://example.com/feed.xml": testFeed("Example"),
+	}}
+	s, store, stdout := newTestState(t, fetcher, "")
+	user := loginTestUser(t, s, "alice")
+
+	err := handlerAddFeed(s, command{name: "addfeed", arguments: []string{"example", "https://example.com/feed.xml"}}, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := store.GetFeedByUrl(context.Background(), "https://example.com/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Name != "example" || feed.UserID != user.ID {
+		t.Errorf("got feed %q added by %s, want example added by %s", feed.Name, feed.UserID, user.ID)
+	}
+	assertFollowing(t, store, user, "https://example.com/feed.xml")
+	if !strings.Contains(stdout.String(), fmt.Sprintf("user_id: %s\n", user.ID)) {
+		t.Errorf("output %q does not name the user who added the feed", stdout.String())
+	}
+
+	err = handlerAddFeed(s, command{name: "addfeed", arguments: []string{"example", "https://example.com/feed.xml"}}, user)
+	if !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("adding the feed twice: got %v, want ErrAlreadyExists", err)
+	}
+}
+
+func TestHandlerAddFeedChoosesDiscoveredFeed(t *testing.T) {
+	fetcher := fakeFetcher{
+		feeds: map[string]RSSFeed{
+			"https://example.com/posts.xml":    testFeed("Posts"),
+			"https://example.com/comments.xml": testFeed("Comments"),
+		},
+		pages: map[string][]string{
+			"https://example.com/": {"https://example.com/posts.xml", "https://example.com/comments.xml"},
+		},
+	}
+	s, store, stdout := newTestState(t, fetcher, "2\n")
+	user := loginTestUser(t, s, "alice")
+
+	err := handlerAddFeed(s, command{name: "addfeed", arguments: []string{"comments", "https://example.com/"}}, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "2. Comments (https://example.com/comments.xml)") {
+		t.Errorf("output %q does not list the discovered feeds", stdout.String())
+	}
+	assertFollowing(t, store, user, "https://example.com/comments.xml")
+}
+
+func TestHandlerAddFeedInvalidFeed(t *testing.T) {
+	s, store, _ := newTestState(t, fakeFetcher{}, "")
+	user := loginTestUser(t, s, "alice")
+
+	err := handlerAddFeed(s, command{name: "addfeed", arguments: []string{"missing", "https://example.com/missing.xml"}}, user)
+	if err == nil {
+		t.Fatal("adding a feed that can't be fetched succeeded")
+	}
+	feeds, err := store.GetFeeds(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 0 {
+		t.Errorf("got %d feeds, want none added", len(feeds))
+	}
+}
+
+func TestHandlerFollow(t *testing.T) {
+	fetcher := fakeFetcher{
+		feeds: map[string]RSSFeed{
+			"https://example.com/feed.xml": testFeed("Example"),
+		},
+		pages: map[string][]string{
+			"https://example.com/":      {"https://example.com/feed.xml"},
+			"https://example.com/about": nil,
+		},
+		errs: map[string]error{
+			"https://down.example.com/": errors.New("dial tcp: connection refused"),
+		},
+	}
+	s, store, stdout := newTestState(t, fetcher, "")
+	alice := loginTestUser(t, s, "alice")
+	err := handlerAddFeed(s, command{name: "addfeed", arguments: []string{"example", "https://example.com/feed.xml"}}, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob := loginTestUser(t, s, "bob")
+
+	// following the site's homepage follows the feed it links to
+	stdout.Reset()
+	err = handlerFollow(s, command{name: "follow", arguments: []string{"https://example.com/"}}, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "'bob' has followed 'example'") {
+		t.Errorf("follow printed %q", stdout.String())
+	}
+	assertFollowing(t, store, bob, "https://example.com/feed.xml")
+
+	err = handlerFollow(s, command{name: "follow", arguments: []string{"https://example.com/feed.xml"}}, bob)
+	if !errors.Is(err, ErrAlreadyFollowing) {
+		t.Errorf("following twice: got %v, want ErrAlreadyFollowing", err)
+	}
+	// a missing page, a page without feeds or a non-URL just isn't a feed
+	for _, feedURL := range []string{"https://example.org/feed.xml", "https://example.com/about", "example"} {
+		err = handlerFollow(s, command{name: "follow", arguments: []string{feedURL}}, bob)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("following %s: got %v, want ErrNotFound", feedURL, err)
+		}
+	}
+	err = handlerFollow(s, command{name: "follow", arguments: []string{"https://down.example.com/"}}, bob)
+	if errors.Is(err, ErrNotFound) || err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("following an unreachable site: got %v, want the discovery error", err)
+	}
+
+	err = handlerUnfollow(s, command{name: "unfollow", arguments: []string{"https://example.com/feed.xml"}}, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFollowing(t, store, bob)
+	assertFollowing(t, store, alice, "https://example.com/feed.xml")
+}
+
+func TestHandlerUnfollowNotFollowing(t *testing.T) {
+	s, store, _ := newTestState(t, fakeFetcher{}, "")
+	user := loginTestUser(t, s, "alice")
+	_, err := store.CreateFeed(context.Background(), database.CreateFeedParams{Name: "blog", Url: "https://example.com/feed.xml", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = handlerUnfollow(s, command{name: "unfollow", arguments: []string{"https://example.com/feed.xml"}}, user)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+	if exitCode(err) != exitNotFound {
+		t.Errorf("exit code %d, want %d", exitCode(err), exitNotFound)
+	}
+}
+
+func TestHandlerBrowse(t *testing.T) {
+	s, store, stdout := newTestState(t, fakeFetcher{}, "")
+	user := loginTestUser(t, s, "alice")
+	ctx := context.Background()
+	feed, err := store.CreateFeed(ctx, database.CreateFeedParams{Name: "blog", Url: "https://example.com/feed.xml", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CreateFeedFollow(ctx, database.CreateFeedFollowParams{UserID: user.ID, FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for idx, title := range []string{"Older", "Newer"} {
+		_, err = store.UpsertPost(ctx, database.UpsertPostParams{
+			Title:       title,
+			Url:         "https://example.com/" + strings.ToLower(title),
+			Description: sql.NullString{String: title + " teaser", Valid: true},
+			Content:     sql.NullString{String: "<p>" + title + " body</p>", Valid: true},
+			Author:      sql.NullString{String: title + " Author", Valid: true},
+			PublishedAt: published.Add(time.Duration(idx) * time.Hour),
+			FeedID:      feed.ID,
+			Guid:        title,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = handlerBrowse(s, command{name: "browse", arguments: []string{"1"}}, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "1. Title: Newer\n") || strings.Contains(out, "Older") {
+		t.Errorf("browse 1 printed %q, want only the newest post", out)
+	}
+	if !strings.Contains(out, "-- Description: Newer teaser\n") {
+		t.Errorf("browse printed %q, want the description", out)
+	}
+	if !strings.Contains(out, "-- Author: Newer Author\n") {
+		t.Errorf("browse printed %q, want the author", out)
+	}
+
+	stdout.Reset()
+	err = handlerBrowse(s, command{name: "browse", arguments: []string{"--full", "1"}}, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "-- Content:\nNewer body\n") {
+		t.Errorf("browse --full printed %q, want the content", stdout.String())
+	}
+}
+
+func TestHandlerRefreshDisabledFeed(t *testing.T) {
+	fetcher := fakeFetcher{feeds: map[string]RSSFeed{
+		"https://example.com/feed.xml": testFeed("Example", RSSItem{Title: "Hello", Link: "https://example.com/hello", GUID: "hello"}),
+	}}
+	s, store, stdout := newTestState(t, fetcher, "")
+	user := loginTestUser(t, s, "alice")
+	ctx := context.Background()
+	feed, err := store.CreateFeed(ctx, database.CreateFeedParams{Name: "example", Url: "https://example.com/feed.xml", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.MarkFeedFetchFailed(ctx, database.MarkFeedFetchFailedParams{
+		LastFetchError: nullString("connection refused"),
+		Disable:        true,
+		ID:             feed.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = handlerRefresh(s, command{name: "refresh", arguments: []string{"https://example.com/feed.xml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "'gator enable-feed https://example.com/feed.xml'") {
+		t.Errorf("refresh printed %q, want a note that the feed is disabled", out)
+	}
+	if !strings.Contains(out, "refreshed 'example': 1 new posts\n") {
+		t.Errorf("refresh printed %q, want the new post counted", out)
+	}
+	// the feed stays disabled, and keeps the failures it was disabled after
+	refreshed, err := store.GetFeedByUrl(ctx, feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !refreshed.DisabledAt.Valid || refreshed.ConsecutiveFailures != 1 {
+		t.Errorf("disabled at %v after %d failures, want still disabled after 1", refreshed.DisabledAt, refreshed.ConsecutiveFailures)
+	}
+	if health := feedHealth(refreshed); !strings.Contains(health, "after 1 consecutive failures") {
+		t.Errorf("health = %q", health)
+	}
+}
+
+func TestHandlerFeedLogShowsSkippedItems(t *testing.T) {
+	fetcher := fakeFetcher{feeds: map[string]RSSFeed{
+		"https://example.com/feed.xml": testFeed("Example",
+			RSSItem{Title: "Hello", Link: "https://example.com/hello", GUID: "hello"},
+			RSSItem{Title: "No link", GUID: "no-link"},
+		),
+	}}
+	s, store, stdout := newTestState(t, fetcher, "")
+	user := loginTestUser(t, s, "alice")
+	_, err := store.CreateFeed(context.Background(), database.CreateFeedParams{Name: "example", Url: "https://example.com/feed.xml", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = handlerRefresh(s, command{name: "refresh", arguments: []string{"https://example.com/feed.xml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout.Reset()
+	err = handlerFeedLog(s, command{name: "feed-log", arguments: []string{"https://example.com/feed.xml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "--- new posts: 1\n--- skipped items: 1\n") {
+		t.Errorf("feed-log printed %q, want the skipped item counted", out)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// feedCandidate is a feed found by discoverFeeds.
+type feedCandidate struct {
+	URL   string
+	Title string
+}
+
+// feedLinkTypes are the <link rel="alternate"> types that announce a feed.
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+// wellKnownFeedPaths are tried when a page doesn't announce any feeds.
+var wellKnownFeedPaths = []string{"/feed", "/rss.xml", "/atom.xml", "/index.xml"}
+
+// errNotAPage and errNoFeedFound are the discovery failures that only mean
+// there is no feed at a URL, rather than that looking for one failed.
+var (
+	errNotAPage    = errors.New("not a web page")
+	errNoFeedFound = errors.New("no feed found")
+)
+
+// httpStatusError is a response with a status other than 200 OK.
+type httpStatusError struct {
+	status string
+}
+
+func (e httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %s", e.status)
+}
+
+// isNoFeedError reports whether a discovery error only means that there is
+// no feed at the URL.
+func isNoFeedError(err error) bool {
+	var statusErr httpStatusError
+	return errors.Is(err, errNotAPage) || errors.Is(err, errNoFeedFound) || errors.As(err, &statusErr)
+}
+
+// maxDiscoveryPageSize bounds how much of an HTML page is searched for
+// feed links. Feeds themselves are parsed in full, as fetchFeed does.
+const maxDiscoveryPageSize = 5 << 20
+
+// discoverFeeds returns the feeds available at pageURL. A feed URL is
+// returned as-is; for an HTML page, the feeds announced in its <link>
+// tags are returned, or failing that any feeds at well-known paths on the
+// same site. Every candidate has been fetched and parsed successfully.
+func discoverFeeds(ctx context.Context, pageURL string, timeoutSec int) ([]feedCandidate, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "gator")
+	client := &http.Client{
+		Timeout: time.Duration(timeoutSec) * time.Second,
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, httpStatusError{status: res.Status}
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := parseFeed(data, res.Header.Get("Content-Type"))
+	if err == nil {
+		unescapeAndTrimFeed(&feed)
+		return []feedCandidate{{URL: pageURL, Title: feed.Channel.Title}}, nil
+	}
+
+	if len(data) > maxDiscoveryPageSize {
+		data = data[:maxDiscoveryPageSize]
+	}
+	// relative links resolve against the URL after any redirects
+	links := htmlFeedLinks(data, res.Request.URL)
+	if len(links) == 0 {
+		for _, path := range wellKnownFeedPaths {
+			links = append(links, feedCandidate{URL: res.Request.URL.ResolveReference(&url.URL{Path: path}).String()})
+		}
+	}
+
+	var candidates []feedCandidate
+	for _, link := range links {
+		fetched, err := fetchFeed(ctx, link.URL, timeoutSec, "", "")
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		if len(link.Title) == 0 {
+			link.Title = fetched.Feed.Channel.Title
+		}
+		candidates = append(candidates, link)
+	}
+	return candidates, nil
+}
+
+// htmlFeedLinks returns the feeds announced by <link rel="alternate">
+// tags, resolved against the page URL or its <base href>.
+func htmlFeedLinks(data []byte, pageURL *url.URL) []feedCandidate {
+	base := pageURL
+	baseSet := false
+	var links []feedCandidate
+	seen := map[string]bool{}
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return links
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+		token := tokenizer.Token()
+		attrs := map[string]string{}
+		for _, attr := range token.Attr {
+			attrs[attr.Key] = strings.TrimSpace(attr.Val)
+		}
+		switch token.Data {
+		case "base":
+			// only the first <base> counts
+			if !baseSet && len(attrs["href"]) != 0 {
+				baseSet = true
+				resolved, err := pageURL.Parse(attrs["href"])
+				if err == nil {
+					base = resolved
+				}
+			}
+		case "link":
+			if !hasLinkRel(attrs["rel"], "alternate") || !feedLinkTypes[strings.ToLower(attrs["type"])] {
+				continue
+			}
+			href, err := base.Parse(attrs["href"])
+			if err != nil || len(attrs["href"]) == 0 || seen[href.String()] {
+				continue
+			}
+			seen[href.String()] = true
+			links = append(links, feedCandidate{URL: href.String(), Title: attrs["title"]})
+		}
+	}
+}
+
+// hasLinkRel reports whether a space-separated rel attribute contains want.
+func hasLinkRel(rel string, want string) bool {
+	for _, value := range strings.Fields(rel) {
+		if strings.EqualFold(value, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// chooseFeedCandidate returns the only candidate, or lists them on out and
+// asks which one to use.
+func chooseFeedCandidate(candidates []feedCandidate, in io.Reader, out io.Writer) (feedCandidate, error) {
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	fmt.Fprintf(out, "found %d feeds:\n", len(candidates))
+	for idx, candidate := range candidates {
+		if len(candidate.Title) == 0 {
+			fmt.Fprintf(out, "%d. %s\n", idx+1, candidate.URL)
+			continue
+		}
+		fmt.Fprintf(out, "%d. %s (%s)\n", idx+1, candidate.Title, candidate.URL)
+	}
+	fmt.Fprintf(out, "choose a feed [1-%d]: ", len(candidates))
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && len(line) == 0 {
+		return feedCandidate{}, fmt.Errorf("no feed chosen")
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 1 || choice > len(candidates) {
+		return feedCandidate{}, fmt.Errorf("invalid choice %q (expected a number from 1 to %d)", strings.TrimSpace(line), len(candidates))
+	}
+	return candidates[choice-1], nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverFeedsLargeFeed(t *testing.T) {
+	// a feed bigger than maxDiscoveryPageSize is still recognised as a feed
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0"?><rss version="2.0"><channel><title>Big Feed</title><link>https://example.com/</link><description>big</description>`)
+	for body.Len() <= maxDiscoveryPageSize {
+		body.WriteString(`<item><title>Post</title><link>https://example.com/post</link><description>` + strings.Repeat("x", 4096) + `</description></item>`)
+	}
+	body.WriteString(`</channel></rss>`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(body.String()))
+	}))
+	defer server.Close()
+
+	candidates, err := discoverFeeds(context.Background(), server.URL+"/feed.xml", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 1 || candidates[0].URL != server.URL+"/feed.xml" || candidates[0].Title != "Big Feed" {
+		t.Errorf("got %+v, want the feed itself", candidates)
+	}
+}
+
+func TestDiscoverFeedsHTMLLinks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+<link rel="alternate" type="application/rss+xml" title="Posts" href="/posts.xml">
+<link rel="alternate" type="application/atom+xml" href="missing.xml">
+<link rel="stylesheet" href="/style.css">
+</head><body></body></html>`))
+	})
+	mux.HandleFunc("/posts.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write(readFixture(t, "rss.xml"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	candidates, err := discoverFeeds(context.Background(), server.URL+"/", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// missing.xml 404s, so only the working feed is offered
+	if len(candidates) != 1 || candidates[0].URL != server.URL+"/posts.xml" || candidates[0].Title != "Posts" {
+		t.Errorf("got %+v, want only %s/posts.xml", candidates, server.URL)
+	}
+}
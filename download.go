@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-fleming/gator/internal/database"
+)
+
+// downloadResult describes an enclosure saved by downloadEnclosure.
+type downloadResult struct {
+	Path    string
+	Bytes   int64
+	Sha256  string
+	Resumed bool
+}
+
+// downloadClient gives up on a server that doesn't respond and bounds how
+// long one attempt may take; a download cut short is resumed by the next.
+var downloadClient = &http.Client{
+	Timeout: time.Hour,
+	Transport: func() http.RoundTripper {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = 30 * time.Second
+		return transport
+	}(),
+}
+
+// downloadEnclosure saves an enclosure into dir. The body is written to a
+// .part file that is renamed once complete, so an interrupted download is
+// resumed with a Range request the next time it is attempted. The response's
+// validator is kept beside the .part file and sent as If-Range, so a file
+// that has changed since is downloaded again from the start.
+func downloadEnclosure(ctx context.Context, enclosure database.Enclosure, dir string) (downloadResult, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return downloadResult{}, err
+	}
+	dest := filepath.Join(dir, enclosureFileName(enclosure))
+	partial := dest + ".part"
+	validatorPath := partial + ".validator"
+	result := downloadResult{Path: dest}
+
+	_, err = os.Stat(dest)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return result, err
+	}
+	if err == nil {
+		// finished by an earlier run that couldn't record it
+		return finishDownload(result)
+	}
+
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return result, err
+	}
+	defer file.Close()
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return result, err
+	}
+	validator := ""
+	if offset > 0 {
+		data, err := os.ReadFile(validatorPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, err
+		}
+		validator = string(data)
+	}
+
+	res, err := requestEnclosure(ctx, enclosure.Url, offset, validator)
+	if err != nil {
+		return result, err
+	}
+	defer func() {
+		res.Body.Close()
+	}()
+
+	if res.StatusCode == http.StatusPartialContent {
+		start, ok := contentRangeStart(res.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			// not the rest of the partial file, so start over
+			res.Body.Close()
+			res, err = requestEnclosure(ctx, enclosure.Url, 0, "")
+			if err != nil {
+				return result, err
+			}
+			if res.StatusCode == http.StatusPartialContent {
+				return result, fmt.Errorf("unexpected HTTP status %s for a request without a range", res.Status)
+			}
+		}
+	}
+
+	complete := false
+	switch res.StatusCode {
+	case http.StatusOK:
+		// a new download, or the server ignored the range or the file
+		// has changed, so start over
+		err = file.Truncate(0)
+		if err != nil {
+			return result, err
+		}
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			return result, err
+		}
+		offset = 0
+		err = saveDownloadValidator(validatorPath, res.Header)
+		if err != nil {
+			return result, err
+		}
+	case http.StatusPartialContent:
+		result.Resumed = true
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset == 0 {
+			return result, fmt.Errorf("unexpected HTTP status %s", res.Status)
+		}
+		// the partial file already holds the whole body
+		complete = true
+	default:
+		return result, fmt.Errorf("unexpected HTTP status %s", res.Status)
+	}
+
+	if !complete {
+		written, err := io.Copy(file, res.Body)
+		if err != nil {
+			return result, fmt.Errorf("download interrupted after %d bytes: %w", offset+written, err)
+		}
+		if res.ContentLength >= 0 && written != res.ContentLength {
+			return result, fmt.Errorf("download incomplete: got %d of %d bytes", written, res.ContentLength)
+		}
+	}
+	err = file.Close()
+	if err != nil {
+		return result, err
+	}
+	err = os.Rename(partial, dest)
+	if err != nil {
+		return result, err
+	}
+	err = os.Remove(validatorPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return result, err
+	}
+	return finishDownload(result)
+}
+
+// requestEnclosure requests an enclosure from offset onwards. With a
+// validator, the range is only honoured if the file hasn't changed.
+func requestEnclosure(ctx context.Context, enclosureURL string, offset int64, validator string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", enclosureURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "gator")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if len(validator) != 0 {
+			req.Header.Set("If-Range", validator)
+		}
+	}
+	return downloadClient.Do(req)
+}
+
+// contentRangeStart returns the first byte position of a Content-Range
+// header such as "bytes 100-199/200".
+func contentRangeStart(contentRange string) (int64, bool) {
+	rangeSpec, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	first, _, ok := strings.Cut(rangeSpec, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(first), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return start, true
+}
+
+// saveDownloadValidator keeps the validator that If-Range can use for a
+// later resume: a strong ETag, or else Last-Modified. Without one, a resume
+// sends a plain Range request.
+func saveDownloadValidator(validatorPath string, header http.Header) error {
+	validator := header.Get("ETag")
+	if len(validator) == 0 || strings.HasPrefix(validator, "W/") {
+		validator = header.Get("Last-Modified")
+	}
+	if len(validator) == 0 {
+		err := os.Remove(validatorPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return os.WriteFile(validatorPath, []byte(validator), 0644)
+}
+
+// finishDownload fills in the size and SHA-256 checksum of a saved file.
+func finishDownload(result downloadResult) (downloadResult, error) {
+	file, err := os.Open(result.Path)
+	if err != nil {
+		return result, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	result.Bytes, err = io.Copy(hash, file)
+	if err != nil {
+		return result, err
+	}
+	result.Sha256 = hex.EncodeToString(hash.Sum(nil))
+	return result, nil
+}
+
+// enclosureFileName names the file after the last segment of the
+// enclosure's URL. Podcast hosts often reuse names such as "audio.mp3",
+// so it is prefixed with part of the enclosure ID, which also keeps the
+// name stable between attempts so a download can be resumed.
+func enclosureFileName(enclosure database.Enclosure) string {
+	prefix := enclosure.ID.String()[:8]
+	name := ""
+	parsed, err := url.Parse(enclosure.Url)
+	if err == nil && strings.Trim(parsed.Path, "/") != "" {
+		name = path.Base(parsed.Path)
+	}
+	name = strings.Map(func(r rune) rune {
+		if r == '\\' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimLeft(name, "."))
+	if len(name) == 0 {
+		name = "enclosure"
+		if extensions, err := mime.ExtensionsByType(enclosure.MimeType.String); err == nil && len(extensions) > 0 {
+			name += extensions[0]
+		}
+	}
+	return prefix + "-" + name
+}
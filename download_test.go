@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-fleming/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// servedEnclosure serves body with Range and If-Range support, counting
+// the requests that asked for a range.
+func servedEnclosure(t *testing.T, body []byte, etag string) (*httptest.Server, *int) {
+	t.Helper()
+	ranged := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.Header.Get("Range")) != 0 {
+			ranged++
+		}
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "episode.mp3", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), bytes.NewReader(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &ranged
+}
+
+// startDownload leaves a partial download of the enclosure in dir, as an
+// interrupted run would.
+func startDownload(t *testing.T, enclosure database.Enclosure, dir string, partial []byte, validator string) {
+	t.Helper()
+	part := filepath.Join(dir, enclosureFileName(enclosure)) + ".part"
+	err := os.WriteFile(part, partial, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validator) != 0 {
+		err = os.WriteFile(part+".validator", []byte(validator), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func assertDownloaded(t *testing.T, result downloadResult, want []byte) {
+	t.Helper()
+	got, err := os.ReadFile(result.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("saved %q, want %q", got, want)
+	}
+	if result.Bytes != int64(len(want)) {
+		t.Errorf("result.Bytes = %d, want %d", result.Bytes, len(want))
+	}
+	_, err = os.Stat(result.Path + ".part.validator")
+	if err == nil {
+		t.Error("the validator was left behind")
+	}
+}
+
+func TestDownloadEnclosureResumes(t *testing.T) {
+	body := []byte(strings.Repeat("episode audio ", 100))
+	server, ranged := servedEnclosure(t, body, `"v1"`)
+	enclosure := database.Enclosure{ID: uuid.New(), Url: server.URL + "/episode.mp3"}
+	dir := t.TempDir()
+	startDownload(t, enclosure, dir, body[:300], `"v1"`)
+
+	result, err := downloadEnclosure(context.Background(), enclosure, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Resumed || *ranged != 1 {
+		t.Errorf("resumed = %v after %d range requests, want a resumed download", result.Resumed, *ranged)
+	}
+	assertDownloaded(t, result, body)
+}
+
+func TestDownloadEnclosureRestartsChangedFile(t *testing.T) {
+	// the partial file is from a version the server no longer has
+	old := []byte(strings.Repeat("old audio ", 100))
+	body := []byte(strings.Repeat("new audio ", 120))
+	server, _ := servedEnclosure(t, body, `"v2"`)
+	enclosure := database.Enclosure{ID: uuid.New(), Url: server.URL + "/episode.mp3"}
+	dir := t.TempDir()
+	startDownload(t, enclosure, dir, old[:300], `"v1"`)
+
+	result, err := downloadEnclosure(context.Background(), enclosure, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Resumed {
+		t.Error("resumed a file that has changed")
+	}
+	assertDownloaded(t, result, body)
+}
+
+func TestDownloadEnclosureRestartsOnWrongRange(t *testing.T) {
+	body := []byte(strings.Repeat("episode audio ", 100))
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if len(r.Header.Get("Range")) != 0 {
+			// a range the client didn't ask for
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-99/%d", len(body)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(body[:100])
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	enclosure := database.Enclosure{ID: uuid.New(), Url: server.URL + "/episode.mp3"}
+	dir := t.TempDir()
+	startDownload(t, enclosure, dir, body[:300], "")
+
+	result, err := downloadEnclosure(context.Background(), enclosure, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Resumed || requests != 2 {
+		t.Errorf("resumed = %v after %d requests, want a fresh download", result.Resumed, requests)
+	}
+	assertDownloaded(t, result, body)
+}
+
+func TestContentRangeStart(t *testing.T) {
+	tests := []struct {
+		header string
+		want   int64
+		ok     bool
+	}{
+		{"bytes 100-199/200", 100, true},
+		{"bytes 0-99/*", 0, true},
+		{"bytes */200", 0, false},
+		{"items 1-2/3", 0, false},
+		{"", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := contentRangeStart(test.header)
+		if got != test.want || ok != test.ok {
+			t.Errorf("contentRangeStart(%q) = %d, %v; want %d, %v", test.header, got, ok, test.want, test.ok)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// RSSEnclosure is an RSS <enclosure>. Atom enclosure links and JSON Feed
+// attachments are mapped onto it too.
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+	// Duration is set by formats that give each enclosure its own
+	// duration; RSS items use itunes:duration instead.
+	Duration string `xml:"-"`
+}
+
+// MediaContent is a Media RSS <media:content> element.
+type MediaContent struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Duration string `xml:"duration,attr"`
+}
+
+// itemEnclosures returns an item's enclosures followed by any media:content
+// elements that don't repeat them, with itunes:duration applied where an
+// enclosure has no duration of its own.
+func itemEnclosures(item RSSItem) []RSSEnclosure {
+	enclosures := make([]RSSEnclosure, 0, len(item.Enclosures)+len(item.MediaContent))
+	seen := map[string]bool{}
+	for _, enclosure := range item.Enclosures {
+		if len(enclosure.URL) == 0 || seen[enclosure.URL] {
+			continue
+		}
+		seen[enclosure.URL] = true
+		if len(enclosure.Duration) == 0 {
+			enclosure.Duration = item.ITunesDuration
+		}
+		enclosures = append(enclosures, enclosure)
+	}
+	for _, media := range item.MediaContent {
+		if len(media.URL) == 0 || seen[media.URL] {
+			continue
+		}
+		seen[media.URL] = true
+		enclosures = append(enclosures, RSSEnclosure{
+			URL:      media.URL,
+			Type:     media.Type,
+			Length:   media.FileSize,
+			Duration: media.Duration,
+		})
+	}
+	return enclosures
+}
+
+// parseEnclosureLength parses a length in bytes. Publishers often send 0
+// or a placeholder when they don't know it, which is treated as unknown.
+func parseEnclosureLength(value string) (int64, bool) {
+	length, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || length <= 0 {
+		return 0, false
+	}
+	return length, true
+}
+
+// parseEnclosureDuration parses a duration given as seconds, as MM:SS or
+// as HH:MM:SS, the forms allowed for itunes:duration. Fractional seconds,
+// as used by media:content, are truncated.
+func parseEnclosureDuration(value string) (int32, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		return 0, false
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+	var seconds float64
+	for _, part := range parts {
+		number, err := strconv.ParseFloat(part, 64)
+		if err != nil || number < 0 {
+			return 0, false
+		}
+		seconds = seconds*60 + number
+	}
+	if seconds <= 0 {
+		return 0, false
+	}
+	return int32(seconds), true
+}
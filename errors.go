@@ -0,0 +1,100 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Errors that commands report to the user. Each has its own exit code so
+// scripts can tell them apart from other failures.
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrAlreadyExists    = errors.New("already exists")
+	ErrAlreadyFollowing = errors.New("already following")
+	ErrNotLoggedIn      = errors.New("not logged in")
+)
+
+const (
+	exitFailure       = 1
+	exitNotFound      = 3
+	exitAlreadyExists = 4
+	exitNotLoggedIn   = 5
+)
+
+// pqUniqueViolation is the SQLSTATE for a unique constraint violation.
+const pqUniqueViolation = "23505"
+
+// feedFollowsUniqueConstraint is violated by following a feed twice.
+const feedFollowsUniqueConstraint = "feed_follows_user_id_feed_id_key"
+
+// SQLite names the columns rather than the constraint, as in "UNIQUE
+// constraint failed: feed_follows.user_id, feed_follows.feed_id". The
+// driver's error only carries the extended result code and this message,
+// so matching the message is the only way to tell which constraint failed.
+const sqliteFeedFollowsUniqueColumns = "feed_follows.user_id, feed_follows.feed_id"
+
+// commandError is a message for the user that still matches one of the
+// errors above with errors.Is.
+type commandError struct {
+	kind    error
+	message string
+}
+
+func (e commandError) Error() string {
+	return e.message
+}
+
+func (e commandError) Unwrap() error {
+	return e.kind
+}
+
+func newCommandError(kind error, format string, args ...any) error {
+	return commandError{
+		kind:    kind,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+// classifyDBError maps sql.ErrNoRows and unique violations reported by
+// Postgres or SQLite onto the errors above, keeping the original error
+// wrapped. Other errors are returned unchanged.
+func classifyDBError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+		if pqErr.Constraint == feedFollowsUniqueConstraint {
+			return fmt.Errorf("%w: %w", ErrAlreadyFollowing, err)
+		}
+		return fmt.Errorf("%w: %w", ErrAlreadyExists, err)
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		if strings.Contains(sqliteErr.Error(), sqliteFeedFollowsUniqueColumns) {
+			return fmt.Errorf("%w: %w", ErrAlreadyFollowing, err)
+		}
+		return fmt.Errorf("%w: %w", ErrAlreadyExists, err)
+	}
+	return err
+}
+
+// exitCode returns the process exit code for an error returned by a command.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, ErrAlreadyExists), errors.Is(err, ErrAlreadyFollowing):
+		return exitAlreadyExists
+	case errors.Is(err, ErrNotLoggedIn):
+		return exitNotLoggedIn
+	default:
+		return exitFailure
+	}
+}
@@ -5,16 +5,26 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type Config struct {
 	DbURL           string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
 	CurrentUserID   string `json:"current_user_id"`
+	MaxFeedFailures int    `json:"max_feed_failures,omitempty"`
+	LogLevel        string `json:"log_level,omitempty"`
+	LogFormat       string `json:"log_format,omitempty"`
+	DownloadDir     string `json:"download_dir,omitempty"`
 }
 
+// DefaultMaxFeedFailures is used when max_feed_failures is not set.
+const DefaultMaxFeedFailures = 10
+
 const configFileName string = ".gatorconfig.json"
 
+const defaultDownloadDirName = "gator-downloads"
+
 func configFilePath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -41,6 +51,34 @@ func Read() (Config, error) {
 	return cfg, nil
 }
 
+// FeedFailureThreshold returns the number of consecutive failed fetches
+// after which a feed is disabled.
+func (c Config) FeedFailureThreshold() int {
+	if c.MaxFeedFailures <= 0 {
+		return DefaultMaxFeedFailures
+	}
+	return c.MaxFeedFailures
+}
+
+// DownloadDirectory returns the directory enclosures are saved to:
+// download_dir, with a leading ~ expanded, or ~/gator-downloads.
+func (c Config) DownloadDirectory() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("config: get user home dir: %w", err)
+	}
+	switch {
+	case c.DownloadDir == "":
+		return filepath.Join(homeDir, defaultDownloadDirName), nil
+	case c.DownloadDir == "~":
+		return homeDir, nil
+	case strings.HasPrefix(c.DownloadDir, "~/"):
+		return filepath.Join(homeDir, c.DownloadDir[2:]), nil
+	default:
+		return c.DownloadDir, nil
+	}
+}
+
 func (c *Config) SetUser(userName string, userID string) error {
 	c.CurrentUserName = userName
 	c.CurrentUserID = userID
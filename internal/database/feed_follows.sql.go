@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -66,7 +67,8 @@ const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT
     users.name as user_name,
     feeds.name as feed_name,
-    feeds.url as feed_url
+    feeds.url as feed_url,
+    feed_follows.folder
 FROM feed_follows
 JOIN users ON feed_follows.user_id = users.id
 JOIN feeds ON feed_follows.feed_id = feeds.id
@@ -77,6 +79,7 @@ type GetFeedFollowsForUserRow struct {
 	UserName string
 	FeedName string
 	FeedUrl  string
+	Folder   sql.NullString
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, id uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -88,7 +91,12 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, id uuid.UUID) ([]Ge
 	var items []GetFeedFollowsForUserRow
 	for rows.Next() {
 		var i GetFeedFollowsForUserRow
-		if err := rows.Scan(&i.UserName, &i.FeedName, &i.FeedUrl); err != nil {
+		if err := rows.Scan(
+			&i.UserName,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.Folder,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -102,7 +110,7 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, id uuid.UUID) ([]Ge
 	return items, nil
 }
 
-const removeFeedFollow = `-- name: RemoveFeedFollow :exec
+const removeFeedFollow = `-- name: RemoveFeedFollow :execrows
 DELETE FROM feed_follows
 WHERE user_id = $1 AND feed_id = $2
 `
@@ -112,7 +120,29 @@ type RemoveFeedFollowParams struct {
 	FeedID uuid.UUID
 }
 
-func (q *Queries) RemoveFeedFollow(ctx context.Context, arg RemoveFeedFollowParams) error {
-	_, err := q.db.ExecContext(ctx, removeFeedFollow, arg.UserID, arg.FeedID)
+func (q *Queries) RemoveFeedFollow(ctx context.Context, arg RemoveFeedFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeFeedFollow, arg.UserID, arg.FeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedFollowFolder = `-- name: SetFeedFollowFolder :exec
+UPDATE feed_follows
+SET
+    updated_at = NOW(),
+    folder = $1
+WHERE user_id = $2 AND feed_id = $3
+`
+
+type SetFeedFollowFolderParams struct {
+	Folder sql.NullString
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFollowFolder, arg.Folder, arg.UserID, arg.FeedID)
 	return err
 }
@@ -7,10 +7,103 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
 
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET claimed_until = NOW() + ($1::INTEGER * INTERVAL '1 second')
+WHERE id IN (
+    SELECT id
+    FROM feeds
+    WHERE next_fetch_at <= NOW()
+    AND disabled_at IS NULL
+    AND (claimed_until IS NULL OR claimed_until < NOW())
+    ORDER BY next_fetch_at ASC
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, last_fetch_error, etag, last_modified, claimed_until, fetch_interval_seconds, adaptive_interval, next_fetch_at, ttl_minutes, skip_hours, skip_days, update_period, update_frequency, consecutive_failures, disabled_at
+`
+
+type ClaimFeedsToFetchParams struct {
+	LeaseSeconds int32
+	BatchSize    int32
+}
+
+// Leases the unclaimed feeds that are due, most overdue first. SKIP LOCKED
+// lets concurrent aggregators claim disjoint sets of feeds, and the lease
+// keeps a feed from being claimed again until it has been marked fetched
+// or the lease expires.
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch, arg.LeaseSeconds, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.LastFetchError,
+			&i.Etag,
+			&i.LastModified,
+			&i.ClaimedUntil,
+			&i.FetchIntervalSeconds,
+			&i.AdaptiveInterval,
+			&i.NextFetchAt,
+			&i.TtlMinutes,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.UpdatePeriod,
+			&i.UpdateFrequency,
+			&i.ConsecutiveFailures,
+			&i.DisabledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countDueFeeds = `-- name: CountDueFeeds :one
+SELECT
+    COUNT(*) FILTER (WHERE next_fetch_at <= NOW()) AS due,
+    COUNT(*) FILTER (
+        WHERE next_fetch_at <= NOW() - (fetch_interval_seconds * INTERVAL '1 second')
+    ) AS overdue
+FROM feeds
+WHERE disabled_at IS NULL
+`
+
+type CountDueFeedsRow struct {
+	Due     int64
+	Overdue int64
+}
+
+func (q *Queries) CountDueFeeds(ctx context.Context) (CountDueFeedsRow, error) {
+	row := q.db.QueryRowContext(ctx, countDueFeeds)
+	var i CountDueFeedsRow
+	err := row.Scan(&i.Due, &i.Overdue)
+	return i, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (name, url, user_id)
 VALUES (
@@ -18,7 +111,7 @@ VALUES (
     $2,
     $3
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, last_fetch_error, etag, last_modified, claimed_until, fetch_interval_seconds, adaptive_interval, next_fetch_at, ttl_minutes, skip_hours, skip_days, update_period, update_frequency, consecutive_failures, disabled_at
 `
 
 type CreateFeedParams struct {
@@ -38,12 +131,37 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.LastFetchError,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.FetchIntervalSeconds,
+		&i.AdaptiveInterval,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.UpdatePeriod,
+		&i.UpdateFrequency,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
 	)
 	return i, err
 }
 
+const enableFeed = `-- name: EnableFeed :exec
+UPDATE feeds
+SET updated_at = NOW(), disabled_at = NULL, consecutive_failures = 0, next_fetch_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) EnableFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, enableFeed, id)
+	return err
+}
+
 const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at 
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, last_fetch_error, etag, last_modified, claimed_until, fetch_interval_seconds, adaptive_interval, next_fetch_at, ttl_minutes, skip_hours, skip_days, update_period, update_frequency, consecutive_failures, disabled_at 
 FROM feeds
 WHERE url = $1
 LIMIT 1
@@ -60,12 +178,26 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.LastFetchError,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.FetchIntervalSeconds,
+		&i.AdaptiveInterval,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.UpdatePeriod,
+		&i.UpdateFrequency,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, last_fetch_error, etag, last_modified, claimed_until, fetch_interval_seconds, adaptive_interval, next_fetch_at, ttl_minutes, skip_hours, skip_days, update_period, update_frequency, consecutive_failures, disabled_at
 FROM feeds
 `
 
@@ -86,6 +218,20 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.LastFetchError,
+			&i.Etag,
+			&i.LastModified,
+			&i.ClaimedUntil,
+			&i.FetchIntervalSeconds,
+			&i.AdaptiveInterval,
+			&i.NextFetchAt,
+			&i.TtlMinutes,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.UpdatePeriod,
+			&i.UpdateFrequency,
+			&i.ConsecutiveFailures,
+			&i.DisabledAt,
 		); err != nil {
 			return nil, err
 		}
@@ -100,35 +246,104 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
-FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1
+const markFeedFetchFailed = `-- name: MarkFeedFetchFailed :exec
+UPDATE feeds
+SET
+    updated_at = NOW(),
+    last_fetched_at = NOW(),
+    last_fetch_error = $1,
+    claimed_until = NULL,
+    next_fetch_at = NOW() + ($2::INTEGER * INTERVAL '1 second'),
+    consecutive_failures = consecutive_failures + 1,
+    disabled_at = CASE WHEN $3::BOOLEAN THEN COALESCE(disabled_at, NOW()) ELSE disabled_at END
+WHERE id = $4
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
+type MarkFeedFetchFailedParams struct {
+	LastFetchError     sql.NullString
+	NextFetchInSeconds int32
+	Disable            bool
+	ID                 uuid.UUID
+}
+
+func (q *Queries) MarkFeedFetchFailed(ctx context.Context, arg MarkFeedFetchFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFetchFailed,
+		arg.LastFetchError,
+		arg.NextFetchInSeconds,
+		arg.Disable,
+		arg.ID,
 	)
-	return i, err
+	return err
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds
-SET updated_at = NOW(), last_fetched_at = NOW()
-WHERE id = $1
+SET
+    updated_at = NOW(),
+    last_fetched_at = NOW(),
+    last_fetch_error = NULL,
+    etag = $1,
+    last_modified = $2,
+    claimed_until = NULL,
+    fetch_interval_seconds = $3,
+    next_fetch_at = NOW() + ($4::INTEGER * INTERVAL '1 second'),
+    ttl_minutes = $5,
+    skip_hours = $6,
+    skip_days = $7,
+    update_period = $8,
+    update_frequency = $9,
+    consecutive_failures = CASE WHEN disabled_at IS NULL THEN 0 ELSE consecutive_failures END
+WHERE id = $10
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
+type MarkFeedFetchedParams struct {
+	Etag                 sql.NullString
+	LastModified         sql.NullString
+	FetchIntervalSeconds int32
+	NextFetchInSeconds   int32
+	TtlMinutes           sql.NullInt32
+	SkipHours            int32
+	SkipDays             int32
+	UpdatePeriod         sql.NullString
+	UpdateFrequency      sql.NullInt32
+	ID                   uuid.UUID
+}
+
+// Records a successful fetch. A disabled feed keeps the count of failures
+// it was disabled after, which feed health reports.
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFetched,
+		arg.Etag,
+		arg.LastModified,
+		arg.FetchIntervalSeconds,
+		arg.NextFetchInSeconds,
+		arg.TtlMinutes,
+		arg.SkipHours,
+		arg.SkipDays,
+		arg.UpdatePeriod,
+		arg.UpdateFrequency,
+		arg.ID,
+	)
+	return err
+}
+
+const setFeedFetchInterval = `-- name: SetFeedFetchInterval :exec
+UPDATE feeds
+SET
+    updated_at = NOW(),
+    fetch_interval_seconds = $1,
+    adaptive_interval = $2,
+    next_fetch_at = COALESCE(last_fetched_at, NOW()) + ($1::INTEGER * INTERVAL '1 second')
+WHERE id = $3
+`
+
+type SetFeedFetchIntervalParams struct {
+	FetchIntervalSeconds int32
+	AdaptiveInterval     bool
+	ID                   uuid.UUID
+}
+
+func (q *Queries) SetFeedFetchInterval(ctx context.Context, arg SetFeedFetchIntervalParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFetchInterval, arg.FetchIntervalSeconds, arg.AdaptiveInterval, arg.ID)
 	return err
 }
@@ -11,14 +11,53 @@ import (
 	"github.com/google/uuid"
 )
 
+type Enclosure struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	PostID          uuid.UUID
+	Url             string
+	MimeType        sql.NullString
+	LengthBytes     sql.NullInt64
+	DurationSeconds sql.NullInt32
+	DownloadedAt    sql.NullTime
+	DownloadPath    sql.NullString
+	Sha256          sql.NullString
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                   uuid.UUID
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	Name                 string
+	Url                  string
+	UserID               uuid.UUID
+	LastFetchedAt        sql.NullTime
+	LastFetchError       sql.NullString
+	Etag                 sql.NullString
+	LastModified         sql.NullString
+	ClaimedUntil         sql.NullTime
+	FetchIntervalSeconds int32
+	AdaptiveInterval     bool
+	NextFetchAt          time.Time
+	TtlMinutes           sql.NullInt32
+	SkipHours            int32
+	SkipDays             int32
+	UpdatePeriod         sql.NullString
+	UpdateFrequency      sql.NullInt32
+	ConsecutiveFailures  int32
+	DisabledAt           sql.NullTime
+}
+
+type FeedFetch struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	FeedID       uuid.UUID
+	StatusCode   sql.NullInt32
+	DurationMs   int32
+	NewPosts     int32
+	Error        sql.NullString
+	SkippedItems int32
 }
 
 type FeedFollow struct {
@@ -27,6 +66,7 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Folder    sql.NullString
 }
 
 type Post struct {
@@ -38,6 +78,9 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt time.Time
 	FeedID      uuid.UUID
+	Content     sql.NullString
+	Guid        string
+	Author      sql.NullString
 }
 
 type User struct {
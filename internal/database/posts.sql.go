@@ -13,40 +13,43 @@ import (
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts (
-    title,
-    url,
-    description,
-    published_at,
-    feed_id
+const adoptLegacyPost = `-- name: AdoptLegacyPost :exec
+UPDATE posts
+SET guid = $1
+WHERE feed_id = $2
+AND url = $3
+AND guid = url
+AND guid <> $1
+AND NOT EXISTS (
+    SELECT 1
+    FROM posts AS existing
+    WHERE existing.feed_id = $2
+    AND existing.guid = $1
 )
-VALUES (
-    $1,
-    $2,
-    $3,
-    $4,
-    $5
-)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
 `
 
-type CreatePostParams struct {
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+type AdoptLegacyPostParams struct {
+	Guid   string
+	FeedID uuid.UUID
+	Url    string
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
-	)
+// Posts stored before guids were tracked were given their link as their
+// guid. Gives such a post the item's real guid, unless the feed already
+// has a post with it, so the item updates the post instead of being
+// stored again.
+func (q *Queries) AdoptLegacyPost(ctx context.Context, arg AdoptLegacyPostParams) error {
+	_, err := q.db.ExecContext(ctx, adoptLegacyPost, arg.Guid, arg.FeedID, arg.Url)
+	return err
+}
+
+const getPost = `-- name: GetPost :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, guid, author FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -57,6 +60,9 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Content,
+		&i.Guid,
+		&i.Author,
 	)
 	return i, err
 }
@@ -70,7 +76,9 @@ SELECT
 	posts.created_at AS created_at,
 	posts.updated_at AS updated_at,
 	posts.published_at AS published_at,
-	posts.feed_id AS feed_id
+	posts.feed_id AS feed_id,
+	posts.content AS content,
+	posts.author AS author
 FROM posts
 JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
 JOIN users ON feed_follows.user_id = users.id
@@ -93,6 +101,8 @@ type GetPostsForUserRow struct {
 	UpdatedAt   time.Time
 	PublishedAt time.Time
 	FeedID      uuid.UUID
+	Content     sql.NullString
+	Author      sql.NullString
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
@@ -113,6 +123,8 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.UpdatedAt,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Content,
+			&i.Author,
 		); err != nil {
 			return nil, err
 		}
@@ -126,3 +138,95 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts (
+    title,
+    url,
+    description,
+    published_at,
+    feed_id,
+    content,
+    guid,
+    author
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET
+    title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    author = EXCLUDED.author,
+    updated_at = NOW()
+WHERE (posts.title, posts.url, posts.description, posts.content, posts.author)
+    IS DISTINCT FROM (EXCLUDED.title, EXCLUDED.url, EXCLUDED.description, EXCLUDED.content, EXCLUDED.author)
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, guid, author, (xmax = 0) AS inserted
+`
+
+type UpsertPostParams struct {
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	Guid        string
+	Author      sql.NullString
+}
+
+type UpsertPostRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	Guid        string
+	Author      sql.NullString
+	Inserted    bool
+}
+
+// Stores an item by its feed-scoped guid. A known item is only updated,
+// bumping updated_at, when its title, link, body or author has changed; otherwise
+// no row is returned. inserted is true for new posts.
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (UpsertPostRow, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Content,
+		arg.Guid,
+		arg.Author,
+	)
+	var i UpsertPostRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.Guid,
+		&i.Author,
+		&i.Inserted,
+	)
+	return i, err
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Storage is a database gator can use, whichever engine it runs on.
+// Queries called directly run on their own; ExecTx runs several in one
+// transaction.
+type Storage interface {
+	Querier
+	ExecTx(ctx context.Context, fn func(q Querier) error) error
+}
+
+// Store is the Postgres Storage, which adds transactions to Queries.
+type Store struct {
+	*Queries
+	db   *sql.DB
+	wrap func(DBTX) DBTX
+}
+
+// NewStore returns a Store for db. If wrap is not nil, every query runs
+// through wrap applied to db or, inside ExecTx, to the transaction, for
+// instance to instrument it.
+func NewStore(db *sql.DB, wrap func(DBTX) DBTX) *Store {
+	if wrap == nil {
+		wrap = func(dbtx DBTX) DBTX { return dbtx }
+	}
+	return &Store{
+		Queries: New(wrap(db)),
+		db:      db,
+		wrap:    wrap,
+	}
+}
+
+// ExecTx calls fn with queries bound to a new transaction, which is
+// committed if fn succeeds and rolled back otherwise.
+func (s *Store) ExecTx(ctx context.Context, fn func(q Querier) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	err = fn(New(s.wrap(tx)))
+	if err != nil {
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil {
+			return fmt.Errorf("%w (rollback: %v)", err, rollbackErr)
+		}
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+var _ Storage = (*Store)(nil)
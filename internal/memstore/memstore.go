@@ -0,0 +1,675 @@
+// Package memstore is an in-memory database.Storage for tests. It keeps
+// the tables in slices and follows the Postgres schema: defaults,
+// ordering, cascading deletes and sql.ErrNoRows all match, and a broken
+// unique or foreign key constraint is reported with the same *pq.Error
+// Postgres would return, so errors are classified the same way.
+package memstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a-fleming/gator/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// SQLSTATE codes for the constraint violations Store reports.
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// defaultFetchIntervalSeconds is the default of feeds.fetch_interval_seconds.
+const defaultFetchIntervalSeconds = 3600
+
+// Store is an in-memory database.Storage. The zero value is not usable;
+// create one with New.
+type Store struct {
+	mu     sync.Mutex
+	tables tables
+	// now returns the current time, which stands in for NOW()
+	now func() time.Time
+}
+
+type tables struct {
+	users       []database.User
+	feeds       []database.Feed
+	feedFollows []database.FeedFollow
+	feedFetches []database.FeedFetch
+	posts       []database.Post
+	enclosures  []database.Enclosure
+}
+
+// New returns an empty Store.
+func New() *Store {
+	return &Store{
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// SetClock makes the Store read the current time from now, so tests can
+// control when feeds fall due.
+func (s *Store) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// ExecTx calls fn with queries that work on a copy of the tables, which
+// replaces them if fn succeeds. Other callers wait until it returns.
+func (s *Store) ExecTx(ctx context.Context, fn func(q database.Querier) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx := &Store{
+		tables: s.tables.clone(),
+		now:    s.now,
+	}
+	err := fn(tx)
+	if err != nil {
+		return err
+	}
+	s.tables = tx.tables
+	return nil
+}
+
+var _ database.Storage = (*Store)(nil)
+
+func (t tables) clone() tables {
+	return tables{
+		users:       slices.Clone(t.users),
+		feeds:       slices.Clone(t.feeds),
+		feedFollows: slices.Clone(t.feedFollows),
+		feedFetches: slices.Clone(t.feedFetches),
+		posts:       slices.Clone(t.posts),
+		enclosures:  slices.Clone(t.enclosures),
+	}
+}
+
+func uniqueError(table string, constraint string) error {
+	return &pq.Error{
+		Code:       uniqueViolation,
+		Message:    fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+func foreignKeyError(table string, constraint string) error {
+	return &pq.Error{
+		Code:       foreignKeyViolation,
+		Message:    fmt.Sprintf("insert or update on table %q violates foreign key constraint %q", table, constraint),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+func (s *Store) userIndex(id uuid.UUID) int {
+	return slices.IndexFunc(s.tables.users, func(user database.User) bool {
+		return user.ID == id
+	})
+}
+
+func (s *Store) feedIndex(id uuid.UUID) int {
+	return slices.IndexFunc(s.tables.feeds, func(feed database.Feed) bool {
+		return feed.ID == id
+	})
+}
+
+func (s *Store) postIndex(id uuid.UUID) int {
+	return slices.IndexFunc(s.tables.posts, func(post database.Post) bool {
+		return post.ID == id
+	})
+}
+
+func (s *Store) isFollowing(userID uuid.UUID, feedID uuid.UUID) bool {
+	return slices.ContainsFunc(s.tables.feedFollows, func(follow database.FeedFollow) bool {
+		return follow.UserID == userID && follow.FeedID == feedID
+	})
+}
+
+func (s *Store) CreateUser(ctx context.Context, name string) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.ContainsFunc(s.tables.users, func(user database.User) bool { return user.Name == name }) {
+		return database.User{}, uniqueError("users", "users_name_key")
+	}
+	now := s.now()
+	user := database.User{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      name,
+	}
+	s.tables.users = append(s.tables.users, user)
+	return user, nil
+}
+
+func (s *Store) GetUser(ctx context.Context, name string) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.tables.users {
+		if user.Name == name {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (s *Store) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := s.userIndex(id)
+	if idx < 0 {
+		return database.User{}, sql.ErrNoRows
+	}
+	return s.tables.users[idx], nil
+}
+
+func (s *Store) GetUsers(ctx context.Context) ([]database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.tables.users), nil
+}
+
+// Reset deletes every user, which cascades to everything else.
+func (s *Store) Reset(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tables = tables{}
+	return nil
+}
+
+func (s *Store) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, feed := range s.tables.feeds {
+		if feed.Name == arg.Name {
+			return database.Feed{}, uniqueError("feeds", "feeds_name_key")
+		}
+		if feed.Url == arg.Url {
+			return database.Feed{}, uniqueError("feeds", "feeds_url_key")
+		}
+	}
+	if s.userIndex(arg.UserID) < 0 {
+		return database.Feed{}, foreignKeyError("feeds", "feeds_user_id_fkey")
+	}
+	now := s.now()
+	feed := database.Feed{
+		ID:                   uuid.New(),
+		CreatedAt:            now,
+		UpdatedAt:            now,
+		Name:                 arg.Name,
+		Url:                  arg.Url,
+		UserID:               arg.UserID,
+		FetchIntervalSeconds: defaultFetchIntervalSeconds,
+		NextFetchAt:          now,
+	}
+	s.tables.feeds = append(s.tables.feeds, feed)
+	return feed, nil
+}
+
+func (s *Store) EnableFeed(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := s.feedIndex(id)
+	if idx < 0 {
+		return nil
+	}
+	now := s.now()
+	feed := &s.tables.feeds[idx]
+	feed.UpdatedAt = now
+	feed.DisabledAt = sql.NullTime{}
+	feed.ConsecutiveFailures = 0
+	feed.NextFetchAt = now
+	return nil
+}
+
+func (s *Store) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.tables.feeds), nil
+}
+
+func (s *Store) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, feed := range s.tables.feeds {
+		if feed.Url == url {
+			return feed, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+// ClaimFeedsToFetch leases the unclaimed feeds that are due, most overdue
+// first.
+func (s *Store) ClaimFeedsToFetch(ctx context.Context, arg database.ClaimFeedsToFetchParams) ([]database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var due []int
+	for idx, feed := range s.tables.feeds {
+		claimed := feed.ClaimedUntil.Valid && !feed.ClaimedUntil.Time.Before(now)
+		if !feed.NextFetchAt.After(now) && !feed.DisabledAt.Valid && !claimed {
+			due = append(due, idx)
+		}
+	}
+	slices.SortStableFunc(due, func(a int, b int) int {
+		return s.tables.feeds[a].NextFetchAt.Compare(s.tables.feeds[b].NextFetchAt)
+	})
+	if len(due) > int(arg.BatchSize) {
+		due = due[:max(arg.BatchSize, 0)]
+	}
+	var claimed []database.Feed
+	for _, idx := range due {
+		feed := &s.tables.feeds[idx]
+		feed.ClaimedUntil = sql.NullTime{Time: now.Add(time.Duration(arg.LeaseSeconds) * time.Second), Valid: true}
+		claimed = append(claimed, *feed)
+	}
+	return claimed, nil
+}
+
+func (s *Store) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := s.feedIndex(arg.ID)
+	if idx < 0 {
+		return nil
+	}
+	now := s.now()
+	feed := &s.tables.feeds[idx]
+	feed.UpdatedAt = now
+	feed.LastFetchedAt = sql.NullTime{Time: now, Valid: true}
+	feed.LastFetchError = sql.NullString{}
+	feed.Etag = arg.Etag
+	feed.LastModified = arg.LastModified
+	feed.ClaimedUntil = sql.NullTime{}
+	feed.FetchIntervalSeconds = arg.FetchIntervalSeconds
+	feed.NextFetchAt = now.Add(time.Duration(arg.NextFetchInSeconds) * time.Second)
+	feed.TtlMinutes = arg.TtlMinutes
+	feed.SkipHours = arg.SkipHours
+	feed.SkipDays = arg.SkipDays
+	feed.UpdatePeriod = arg.UpdatePeriod
+	feed.UpdateFrequency = arg.UpdateFrequency
+	if !feed.DisabledAt.Valid {
+		feed.ConsecutiveFailures = 0
+	}
+	return nil
+}
+
+func (s *Store) MarkFeedFetchFailed(ctx context.Context, arg database.MarkFeedFetchFailedParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := s.feedIndex(arg.ID)
+	if idx < 0 {
+		return nil
+	}
+	now := s.now()
+	feed := &s.tables.feeds[idx]
+	feed.UpdatedAt = now
+	feed.LastFetchedAt = sql.NullTime{Time: now, Valid: true}
+	feed.LastFetchError = arg.LastFetchError
+	feed.ClaimedUntil = sql.NullTime{}
+	feed.NextFetchAt = now.Add(time.Duration(arg.NextFetchInSeconds) * time.Second)
+	feed.ConsecutiveFailures++
+	if arg.Disable && !feed.DisabledAt.Valid {
+		feed.DisabledAt = sql.NullTime{Time: now, Valid: true}
+	}
+	return nil
+}
+
+func (s *Store) SetFeedFetchInterval(ctx context.Context, arg database.SetFeedFetchIntervalParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := s.feedIndex(arg.ID)
+	if idx < 0 {
+		return nil
+	}
+	now := s.now()
+	feed := &s.tables.feeds[idx]
+	feed.UpdatedAt = now
+	feed.FetchIntervalSeconds = arg.FetchIntervalSeconds
+	feed.AdaptiveInterval = arg.AdaptiveInterval
+	from := now
+	if feed.LastFetchedAt.Valid {
+		from = feed.LastFetchedAt.Time
+	}
+	feed.NextFetchAt = from.Add(time.Duration(arg.FetchIntervalSeconds) * time.Second)
+	return nil
+}
+
+func (s *Store) CountDueFeeds(ctx context.Context) (database.CountDueFeedsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var count database.CountDueFeedsRow
+	for _, feed := range s.tables.feeds {
+		if feed.DisabledAt.Valid {
+			continue
+		}
+		if !feed.NextFetchAt.After(now) {
+			count.Due++
+		}
+		if !feed.NextFetchAt.After(now.Add(-time.Duration(feed.FetchIntervalSeconds) * time.Second)) {
+			count.Overdue++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) CreateFeedFetch(ctx context.Context, arg database.CreateFeedFetchParams) (database.FeedFetch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.feedIndex(arg.FeedID) < 0 {
+		return database.FeedFetch{}, foreignKeyError("feed_fetches", "feed_fetches_feed_id_fkey")
+	}
+	fetch := database.FeedFetch{
+		ID:           uuid.New(),
+		CreatedAt:    s.now(),
+		FeedID:       arg.FeedID,
+		StatusCode:   arg.StatusCode,
+		DurationMs:   arg.DurationMs,
+		NewPosts:     arg.NewPosts,
+		SkippedItems: arg.SkippedItems,
+		Error:        arg.Error,
+	}
+	s.tables.feedFetches = append(s.tables.feedFetches, fetch)
+	return fetch, nil
+}
+
+// GetFeedFetchesForFeed returns the latest fetches of a feed, newest first.
+func (s *Store) GetFeedFetchesForFeed(ctx context.Context, arg database.GetFeedFetchesForFeedParams) ([]database.FeedFetch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var fetches []database.FeedFetch
+	for _, fetch := range slices.Backward(s.tables.feedFetches) {
+		if fetch.FeedID == arg.FeedID {
+			fetches = append(fetches, fetch)
+		}
+	}
+	slices.SortStableFunc(fetches, func(a database.FeedFetch, b database.FeedFetch) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return limit(fetches, arg.Limit), nil
+}
+
+func (s *Store) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	userIdx := s.userIndex(arg.UserID)
+	if userIdx < 0 {
+		return database.CreateFeedFollowRow{}, foreignKeyError("feed_follows", "feed_follows_user_id_fkey")
+	}
+	feedIdx := s.feedIndex(arg.FeedID)
+	if feedIdx < 0 {
+		return database.CreateFeedFollowRow{}, foreignKeyError("feed_follows", "feed_follows_feed_id_fkey")
+	}
+	if s.isFollowing(arg.UserID, arg.FeedID) {
+		return database.CreateFeedFollowRow{}, uniqueError("feed_follows", "feed_follows_user_id_feed_id_key")
+	}
+	now := s.now()
+	follow := database.FeedFollow{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	}
+	s.tables.feedFollows = append(s.tables.feedFollows, follow)
+	return database.CreateFeedFollowRow{
+		ID:        follow.ID,
+		CreatedAt: follow.CreatedAt,
+		UpdatedAt: follow.UpdatedAt,
+		UserID:    follow.UserID,
+		FeedID:    follow.FeedID,
+		UserName:  s.tables.users[userIdx].Name,
+		FeedName:  s.tables.feeds[feedIdx].Name,
+	}, nil
+}
+
+func (s *Store) GetFeedFollowsForUser(ctx context.Context, id uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	userIdx := s.userIndex(id)
+	if userIdx < 0 {
+		return nil, nil
+	}
+	var rows []database.GetFeedFollowsForUserRow
+	for _, follow := range s.tables.feedFollows {
+		if follow.UserID != id {
+			continue
+		}
+		feed := s.tables.feeds[s.feedIndex(follow.FeedID)]
+		rows = append(rows, database.GetFeedFollowsForUserRow{
+			UserName: s.tables.users[userIdx].Name,
+			FeedName: feed.Name,
+			FeedUrl:  feed.Url,
+			Folder:   follow.Folder,
+		})
+	}
+	return rows, nil
+}
+
+func (s *Store) RemoveFeedFollow(ctx context.Context, arg database.RemoveFeedFollowParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := len(s.tables.feedFollows)
+	s.tables.feedFollows = slices.DeleteFunc(s.tables.feedFollows, func(follow database.FeedFollow) bool {
+		return follow.UserID == arg.UserID && follow.FeedID == arg.FeedID
+	})
+	return int64(before - len(s.tables.feedFollows)), nil
+}
+
+func (s *Store) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for idx := range s.tables.feedFollows {
+		follow := &s.tables.feedFollows[idx]
+		if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+			follow.UpdatedAt = s.now()
+			follow.Folder = arg.Folder
+		}
+	}
+	return nil
+}
+
+// AdoptLegacyPost gives a post stored with its link as its guid the
+// item's real guid, unless the feed already has a post with it.
+func (s *Store) AdoptLegacyPost(ctx context.Context, arg database.AdoptLegacyPostParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	taken := slices.ContainsFunc(s.tables.posts, func(post database.Post) bool {
+		return post.FeedID == arg.FeedID && post.Guid == arg.Guid
+	})
+	if taken {
+		return nil
+	}
+	for idx := range s.tables.posts {
+		post := &s.tables.posts[idx]
+		if post.FeedID == arg.FeedID && post.Url == arg.Url && post.Guid == post.Url {
+			post.Guid = arg.Guid
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetPost(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := s.postIndex(id)
+	if idx < 0 {
+		return database.Post{}, sql.ErrNoRows
+	}
+	return s.tables.posts[idx], nil
+}
+
+// GetPostsForUser returns the latest posts from the feeds a user follows,
+// newest first.
+func (s *Store) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows []database.GetPostsForUserRow
+	for _, post := range s.tables.posts {
+		if !s.isFollowing(arg.ID, post.FeedID) {
+			continue
+		}
+		rows = append(rows, database.GetPostsForUserRow{
+			Title:       post.Title,
+			Url:         post.Url,
+			Description: post.Description,
+			PostID:      post.ID,
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+			PublishedAt: post.PublishedAt,
+			FeedID:      post.FeedID,
+			Content:     post.Content,
+			Author:      post.Author,
+		})
+	}
+	slices.SortStableFunc(rows, func(a database.GetPostsForUserRow, b database.GetPostsForUserRow) int {
+		return b.PublishedAt.Compare(a.PublishedAt)
+	})
+	return limit(rows, arg.Limit), nil
+}
+
+// UpsertPost stores an item by its feed-scoped guid. A known item is only
+// updated when its title, link, body or author has changed; otherwise
+// sql.ErrNoRows is returned.
+func (s *Store) UpsertPost(ctx context.Context, arg database.UpsertPostParams) (database.UpsertPostRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.feedIndex(arg.FeedID) < 0 {
+		return database.UpsertPostRow{}, foreignKeyError("posts", "posts_feed_id_fkey")
+	}
+	now := s.now()
+	idx := slices.IndexFunc(s.tables.posts, func(post database.Post) bool {
+		return post.FeedID == arg.FeedID && post.Guid == arg.Guid
+	})
+	if idx < 0 {
+		post := database.Post{
+			ID:          uuid.New(),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			Title:       arg.Title,
+			Url:         arg.Url,
+			Description: arg.Description,
+			PublishedAt: arg.PublishedAt,
+			FeedID:      arg.FeedID,
+			Content:     arg.Content,
+			Guid:        arg.Guid,
+			Author:      arg.Author,
+		}
+		s.tables.posts = append(s.tables.posts, post)
+		return upsertPostRow(post, true), nil
+	}
+	post := &s.tables.posts[idx]
+	if post.Title == arg.Title && post.Url == arg.Url && post.Description == arg.Description && post.Content == arg.Content && post.Author == arg.Author {
+		return database.UpsertPostRow{}, sql.ErrNoRows
+	}
+	post.Title = arg.Title
+	post.Url = arg.Url
+	post.Description = arg.Description
+	post.Content = arg.Content
+	post.Author = arg.Author
+	post.UpdatedAt = now
+	return upsertPostRow(*post, false), nil
+}
+
+func upsertPostRow(post database.Post, inserted bool) database.UpsertPostRow {
+	return database.UpsertPostRow{
+		ID:          post.ID,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+		Title:       post.Title,
+		Url:         post.Url,
+		Description: post.Description,
+		PublishedAt: post.PublishedAt,
+		FeedID:      post.FeedID,
+		Content:     post.Content,
+		Guid:        post.Guid,
+		Author:      post.Author,
+		Inserted:    inserted,
+	}
+}
+
+func (s *Store) UpsertEnclosure(ctx context.Context, arg database.UpsertEnclosureParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.postIndex(arg.PostID) < 0 {
+		return foreignKeyError("enclosures", "enclosures_post_id_fkey")
+	}
+	now := s.now()
+	idx := slices.IndexFunc(s.tables.enclosures, func(enclosure database.Enclosure) bool {
+		return enclosure.PostID == arg.PostID && enclosure.Url == arg.Url
+	})
+	if idx < 0 {
+		s.tables.enclosures = append(s.tables.enclosures, database.Enclosure{
+			ID:              uuid.New(),
+			CreatedAt:       now,
+			UpdatedAt:       now,
+			PostID:          arg.PostID,
+			Url:             arg.Url,
+			MimeType:        arg.MimeType,
+			LengthBytes:     arg.LengthBytes,
+			DurationSeconds: arg.DurationSeconds,
+		})
+		return nil
+	}
+	enclosure := &s.tables.enclosures[idx]
+	enclosure.MimeType = arg.MimeType
+	enclosure.LengthBytes = arg.LengthBytes
+	enclosure.DurationSeconds = arg.DurationSeconds
+	enclosure.UpdatedAt = now
+	return nil
+}
+
+func (s *Store) GetEnclosuresForPost(ctx context.Context, postID uuid.UUID) ([]database.Enclosure, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var enclosures []database.Enclosure
+	for _, enclosure := range s.tables.enclosures {
+		if enclosure.PostID == postID {
+			enclosures = append(enclosures, enclosure)
+		}
+	}
+	slices.SortStableFunc(enclosures, func(a database.Enclosure, b database.Enclosure) int {
+		if order := a.CreatedAt.Compare(b.CreatedAt); order != 0 {
+			return order
+		}
+		return strings.Compare(a.Url, b.Url)
+	})
+	return enclosures, nil
+}
+
+func (s *Store) MarkEnclosureDownloaded(ctx context.Context, arg database.MarkEnclosureDownloadedParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for idx := range s.tables.enclosures {
+		enclosure := &s.tables.enclosures[idx]
+		if enclosure.ID != arg.ID {
+			continue
+		}
+		now := s.now()
+		enclosure.DownloadedAt = sql.NullTime{Time: now, Valid: true}
+		enclosure.DownloadPath = arg.DownloadPath
+		enclosure.Sha256 = arg.Sha256
+		enclosure.UpdatedAt = now
+	}
+	return nil
+}
+
+// limit applies a LIMIT clause to rows.
+func limit[T any](rows []T, n int32) []T {
+	if int(n) < len(rows) {
+		return rows[:max(n, 0)]
+	}
+	return rows
+}
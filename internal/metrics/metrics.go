@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	FeedFetches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_feed_fetches_total",
+		Help: "Feed fetch attempts by HTTP status code, or \"none\" when no response was received.",
+	}, []string{"status"})
+
+	FeedFetchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "gator_feed_fetch_duration_seconds",
+		Help:    "Time taken to download and parse a feed.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 15, 30, 60},
+	})
+
+	PostsInserted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gator_posts_inserted_total",
+		Help: "Posts stored for the first time.",
+	})
+
+	PostsUpdated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gator_posts_updated_total",
+		Help: "Stored posts whose title, link or content changed.",
+	})
+
+	PostsDuplicate = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gator_posts_duplicate_total",
+		Help: "Posts skipped because they were already stored unchanged.",
+	})
+
+	ParseErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gator_feed_parse_errors_total",
+		Help: "Fetched documents that could not be parsed as a feed.",
+	})
+
+	FeedsDue = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gator_feeds_due",
+		Help: "Enabled feeds whose next fetch time has passed.",
+	})
+
+	FeedsOverdue = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gator_feeds_overdue",
+		Help: "Enabled feeds that have been due for longer than their own fetch interval.",
+	})
+
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gator_db_query_duration_seconds",
+		Help:    "Database query latency by sqlc query name.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"query"})
+)
+
+// Serve exposes the default registry at /metrics on addr until ctx is
+// done. It returns once the listener is open.
+func Serve(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go server.Serve(listener)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	return nil
+}
+
+// DBTX matches database.DBTX, the interface sqlc's Queries run against.
+type DBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+// InstrumentDB wraps db so that every query's latency is recorded in
+// DBQueryDuration, labelled with the name from sqlc's "-- name:" header.
+func InstrumentDB(db DBTX) DBTX {
+	return instrumentedDB{db: db}
+}
+
+type instrumentedDB struct {
+	db DBTX
+}
+
+func (i instrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer observeQuery(query, time.Now())
+	return i.db.ExecContext(ctx, query, args...)
+}
+
+func (i instrumentedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return i.db.PrepareContext(ctx, query)
+}
+
+func (i instrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer observeQuery(query, time.Now())
+	return i.db.QueryContext(ctx, query, args...)
+}
+
+func (i instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer observeQuery(query, time.Now())
+	return i.db.QueryRowContext(ctx, query, args...)
+}
+
+func observeQuery(query string, started time.Time) {
+	DBQueryDuration.WithLabelValues(queryName(query)).Observe(time.Since(started).Seconds())
+}
+
+// queryName extracts "GetFeeds" from a query starting with
+// "-- name: GetFeeds :many".
+func queryName(query string) string {
+	header, _, _ := strings.Cut(query, "\n")
+	name, found := strings.CutPrefix(header, "-- name: ")
+	if !found {
+		return "unknown"
+	}
+	name, _, _ = strings.Cut(name, " ")
+	return name
+}
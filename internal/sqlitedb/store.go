@@ -0,0 +1,264 @@
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/a-fleming/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Store is the SQLite database.Storage. The models generated for both
+// engines have the same fields, so results are converted directly to the
+// database package's types.
+type Store struct {
+	querier
+	db   *sql.DB
+	wrap func(DBTX) DBTX
+}
+
+// NewStore returns a Store for db. If wrap is not nil, every query runs
+// through wrap applied to db or, inside ExecTx, to the transaction, for
+// instance to instrument it.
+func NewStore(db *sql.DB, wrap func(DBTX) DBTX) *Store {
+	if wrap == nil {
+		wrap = func(dbtx DBTX) DBTX { return dbtx }
+	}
+	return &Store{
+		querier: querier{q: New(wrap(db))},
+		db:      db,
+		wrap:    wrap,
+	}
+}
+
+// ExecTx calls fn with queries bound to a new transaction, which is
+// committed if fn succeeds and rolled back otherwise.
+func (s *Store) ExecTx(ctx context.Context, fn func(q database.Querier) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	err = fn(querier{q: New(s.wrap(tx))})
+	if err != nil {
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil {
+			return fmt.Errorf("%w (rollback: %v)", err, rollbackErr)
+		}
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+var _ database.Storage = (*Store)(nil)
+
+// querier implements database.Querier on top of the SQLite queries.
+type querier struct {
+	q *Queries
+}
+
+func convertAll[T, U any](items []T, convert func(T) U) []U {
+	if items == nil {
+		return nil
+	}
+	converted := make([]U, 0, len(items))
+	for _, item := range items {
+		converted = append(converted, convert(item))
+	}
+	return converted
+}
+
+func toFeed(feed Feed) database.Feed {
+	return database.Feed(feed)
+}
+
+func toUser(user User) database.User {
+	return database.User(user)
+}
+
+func toFeedFetch(fetch FeedFetch) database.FeedFetch {
+	return database.FeedFetch(fetch)
+}
+
+func (w querier) AdoptLegacyPost(ctx context.Context, arg database.AdoptLegacyPostParams) error {
+	return w.q.AdoptLegacyPost(ctx, AdoptLegacyPostParams(arg))
+}
+
+func (w querier) ClaimFeedsToFetch(ctx context.Context, arg database.ClaimFeedsToFetchParams) ([]database.Feed, error) {
+	feeds, err := w.q.ClaimFeedsToFetch(ctx, ClaimFeedsToFetchParams{
+		LeaseSeconds: arg.LeaseSeconds,
+		BatchSize:    int64(arg.BatchSize),
+	})
+	return convertAll(feeds, toFeed), err
+}
+
+func (w querier) CountDueFeeds(ctx context.Context) (database.CountDueFeedsRow, error) {
+	row, err := w.q.CountDueFeeds(ctx)
+	return database.CountDueFeedsRow(row), err
+}
+
+func (w querier) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	feed, err := w.q.CreateFeed(ctx, CreateFeedParams(arg))
+	return toFeed(feed), err
+}
+
+func (w querier) CreateFeedFetch(ctx context.Context, arg database.CreateFeedFetchParams) (database.FeedFetch, error) {
+	fetch, err := w.q.CreateFeedFetch(ctx, CreateFeedFetchParams(arg))
+	return toFeedFetch(fetch), err
+}
+
+func (w querier) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	row, err := w.q.CreateFeedFollow(ctx, CreateFeedFollowParams(arg))
+	return database.CreateFeedFollowRow(row), err
+}
+
+func (w querier) CreateUser(ctx context.Context, name string) (database.User, error) {
+	user, err := w.q.CreateUser(ctx, name)
+	return toUser(user), err
+}
+
+func (w querier) EnableFeed(ctx context.Context, id uuid.UUID) error {
+	return w.q.EnableFeed(ctx, id)
+}
+
+func (w querier) GetEnclosuresForPost(ctx context.Context, postID uuid.UUID) ([]database.Enclosure, error) {
+	enclosures, err := w.q.GetEnclosuresForPost(ctx, postID)
+	return convertAll(enclosures, func(enclosure Enclosure) database.Enclosure {
+		return database.Enclosure(enclosure)
+	}), err
+}
+
+func (w querier) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	feed, err := w.q.GetFeedByUrl(ctx, url)
+	return toFeed(feed), err
+}
+
+func (w querier) GetFeedFetchesForFeed(ctx context.Context, arg database.GetFeedFetchesForFeedParams) ([]database.FeedFetch, error) {
+	fetches, err := w.q.GetFeedFetchesForFeed(ctx, GetFeedFetchesForFeedParams{
+		FeedID: arg.FeedID,
+		Limit:  int64(arg.Limit),
+	})
+	return convertAll(fetches, toFeedFetch), err
+}
+
+func (w querier) GetFeedFollowsForUser(ctx context.Context, id uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	rows, err := w.q.GetFeedFollowsForUser(ctx, id)
+	return convertAll(rows, func(row GetFeedFollowsForUserRow) database.GetFeedFollowsForUserRow {
+		return database.GetFeedFollowsForUserRow(row)
+	}), err
+}
+
+func (w querier) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	feeds, err := w.q.GetFeeds(ctx)
+	return convertAll(feeds, toFeed), err
+}
+
+func (w querier) GetPost(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	post, err := w.q.GetPost(ctx, id)
+	return database.Post(post), err
+}
+
+func (w querier) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	rows, err := w.q.GetPostsForUser(ctx, GetPostsForUserParams{
+		ID:    arg.ID,
+		Limit: int64(arg.Limit),
+	})
+	return convertAll(rows, func(row GetPostsForUserRow) database.GetPostsForUserRow {
+		return database.GetPostsForUserRow(row)
+	}), err
+}
+
+func (w querier) GetUser(ctx context.Context, name string) (database.User, error) {
+	user, err := w.q.GetUser(ctx, name)
+	return toUser(user), err
+}
+
+func (w querier) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := w.q.GetUserById(ctx, id)
+	return toUser(user), err
+}
+
+func (w querier) GetUsers(ctx context.Context) ([]database.User, error) {
+	users, err := w.q.GetUsers(ctx)
+	return convertAll(users, toUser), err
+}
+
+func (w querier) MarkEnclosureDownloaded(ctx context.Context, arg database.MarkEnclosureDownloadedParams) error {
+	return w.q.MarkEnclosureDownloaded(ctx, MarkEnclosureDownloadedParams(arg))
+}
+
+func (w querier) MarkFeedFetchFailed(ctx context.Context, arg database.MarkFeedFetchFailedParams) error {
+	return w.q.MarkFeedFetchFailed(ctx, MarkFeedFetchFailedParams(arg))
+}
+
+func (w querier) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error {
+	return w.q.MarkFeedFetched(ctx, MarkFeedFetchedParams(arg))
+}
+
+func (w querier) RemoveFeedFollow(ctx context.Context, arg database.RemoveFeedFollowParams) (int64, error) {
+	return w.q.RemoveFeedFollow(ctx, RemoveFeedFollowParams(arg))
+}
+
+func (w querier) Reset(ctx context.Context) error {
+	return w.q.Reset(ctx)
+}
+
+func (w querier) SetFeedFetchInterval(ctx context.Context, arg database.SetFeedFetchIntervalParams) error {
+	return w.q.SetFeedFetchInterval(ctx, SetFeedFetchIntervalParams(arg))
+}
+
+func (w querier) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) error {
+	return w.q.SetFeedFollowFolder(ctx, SetFeedFollowFolderParams(arg))
+}
+
+func (w querier) UpsertEnclosure(ctx context.Context, arg database.UpsertEnclosureParams) error {
+	return w.q.UpsertEnclosure(ctx, UpsertEnclosureParams(arg))
+}
+
+// UpsertPost inserts the post or, if its guid is already stored, updates
+// it when it has changed. Like the Postgres query, it returns
+// sql.ErrNoRows for an unchanged post.
+func (w querier) UpsertPost(ctx context.Context, arg database.UpsertPostParams) (database.UpsertPostRow, error) {
+	post, err := w.q.InsertPost(ctx, InsertPostParams(arg))
+	if err == nil {
+		return upsertPostRow(post, true), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.UpsertPostRow{}, err
+	}
+	post, err = w.q.UpdateChangedPost(ctx, UpdateChangedPostParams{
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		Content:     arg.Content,
+		Author:      arg.Author,
+		FeedID:      arg.FeedID,
+		Guid:        arg.Guid,
+	})
+	if err != nil {
+		return database.UpsertPostRow{}, err
+	}
+	return upsertPostRow(post, false), nil
+}
+
+func upsertPostRow(post Post, inserted bool) database.UpsertPostRow {
+	return database.UpsertPostRow{
+		ID:          post.ID,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+		Title:       post.Title,
+		Url:         post.Url,
+		Description: post.Description,
+		PublishedAt: post.PublishedAt,
+		FeedID:      post.FeedID,
+		Content:     post.Content,
+		Guid:        post.Guid,
+		Author:      post.Author,
+		Inserted:    inserted,
+	}
+}
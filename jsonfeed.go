@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// JSONFeed covers the fields of JSON Feed 1.0 and 1.1 that gator uses.
+// Version 1.0 has a single author object; 1.1 replaced it with authors.
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            string               `json:"id"`
+	URL           string               `json:"url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Author        *JSONFeedAuthor      `json:"author"`
+	Authors       []JSONFeedAuthor     `json:"authors"`
+	Attachments   []JSONFeedAttachment `json:"attachments"`
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type JSONFeedAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+func parseJSONFeed(data []byte) (RSSFeed, error) {
+	var jsonFeed JSONFeed
+	err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\ufeff")), &jsonFeed)
+	if err != nil {
+		return RSSFeed{}, err
+	}
+	return jsonFeed.toRSSFeed(), nil
+}
+
+// toRSSFeed maps a JSON Feed document onto the RSS model consumed by addPosts.
+func (f JSONFeed) toRSSFeed() RSSFeed {
+	var feed RSSFeed
+	feed.Channel.Title = f.Title
+	feed.Channel.Link = f.HomePageURL
+	feed.Channel.Description = f.Description
+	for _, item := range f.Items {
+		content := item.ContentHTML
+		if len(content) == 0 {
+			content = item.ContentText
+		}
+		description := item.Summary
+		if len(description) == 0 {
+			description = content
+		}
+		pubDate := item.DatePublished
+		if len(pubDate) == 0 {
+			pubDate = item.DateModified
+		}
+		title := item.Title
+		if len(title) == 0 {
+			// title is optional in JSON Feed, e.g. for microblog posts
+			title = item.URL
+		}
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       title,
+			Link:        item.URL,
+			Description: description,
+			PubDate:     pubDate,
+			Author:      item.authorNames(),
+			GUID:        item.ID,
+			Content:     content,
+			Enclosures:  item.enclosures(),
+		})
+	}
+	return feed
+}
+
+func (item JSONFeedItem) authorNames() string {
+	authors := item.Authors
+	if len(authors) == 0 && item.Author != nil {
+		authors = []JSONFeedAuthor{*item.Author}
+	}
+	names := make([]string, 0, len(authors))
+	for _, author := range authors {
+		if len(author.Name) != 0 {
+			names = append(names, author.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func (item JSONFeedItem) enclosures() []RSSEnclosure {
+	var enclosures []RSSEnclosure
+	for _, attachment := range item.Attachments {
+		enclosure := RSSEnclosure{
+			URL:  attachment.URL,
+			Type: attachment.MimeType,
+		}
+		if attachment.SizeInBytes > 0 {
+			enclosure.Length = strconv.FormatInt(attachment.SizeInBytes, 10)
+		}
+		if attachment.DurationInSeconds > 0 {
+			enclosure.Duration = strconv.FormatFloat(attachment.DurationInSeconds, 'f', -1, 64)
+		}
+		enclosures = append(enclosures, enclosure)
+	}
+	return enclosures
+}
@@ -1,13 +1,12 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
 
 	"github.com/a-fleming/gator/internal/config"
-	"github.com/a-fleming/gator/internal/database"
-	_ "github.com/lib/pq"
 )
 
 func main() {
@@ -26,22 +25,42 @@ func main() {
 		os.Exit(1)
 	}
 
-	db, err := sql.Open("postgres", cfg.DbURL)
+	dbStore, migrations, err := openStorage(cfg.DbURL)
 	if err != nil {
 		fmt.Printf("Error: %s\n", err)
 		os.Exit(1)
 	}
-	err = db.Ping()
+	// migrate is how an outdated schema gets fixed, so it skips the check
+	if commandName != "migrate" {
+		err = checkSchemaVersion(context.Background(), migrations)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	logLevel := new(slog.LevelVar)
+	level, err := parseLogLevel(cfg.LogLevel)
 	if err != nil {
-		fmt.Printf("Error: %s\n", err)
+		fmt.Printf("Error: config: %s\n", err)
+		os.Exit(1)
+	}
+	logLevel.Set(level)
+	logger, err := newLogger(os.Stderr, cfg.LogFormat, logLevel)
+	if err != nil {
+		fmt.Printf("Error: config: %s\n", err)
 		os.Exit(1)
 	}
-
-	dbQueries := database.New(db)
 
 	cliState := state{
-		config: &cfg,
-		db:     dbQueries,
+		config:     &cfg,
+		db:         dbStore,
+		logger:     logger,
+		logLevel:   logLevel,
+		migrations: migrations,
+		fetcher:    httpFetcher{},
+		stdin:      os.Stdin,
+		stdout:     os.Stdout,
 	}
 
 	cmds := GetCommands()
@@ -54,6 +73,6 @@ func main() {
 	err = cmds.run(&cliState, cmdToRun)
 	if err != nil {
 		fmt.Printf("%s\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
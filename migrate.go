@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed sql/schema/*.sql sql/sqlite/schema/*.sql
+var embeddedMigrations embed.FS
+
+// newMigrationProvider returns a goose provider for the migrations built
+// into the binary that are in dir, written in dialect.
+func newMigrationProvider(db *sql.DB, dialect goose.Dialect, dir string) (*goose.Provider, error) {
+	schema, err := fs.Sub(embeddedMigrations, dir)
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(dialect, db, schema)
+}
+
+// checkSchemaVersion refuses to run against a database that hasn't had
+// every migration built into the binary applied. A newer schema is
+// accepted so that an older binary keeps working during an upgrade.
+func checkSchemaVersion(ctx context.Context, migrations *goose.Provider) error {
+	current, err := migrations.GetDBVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	sources := migrations.ListSources()
+	expected := sources[len(sources)-1].Version
+	if current < expected {
+		return fmt.Errorf("database schema is at version %d but gator needs version %d; run 'gator migrate up' to upgrade it", current, expected)
+	}
+	return nil
+}
+
+func handlerMigrate(s *state, cmd command) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf("gator migrate: error: the following argument is required: action (up, down, status or version)")
+	}
+	ctx := context.Background()
+	switch cmd.arguments[0] {
+	case "up":
+		results, err := s.migrations.Up(ctx)
+		for _, result := range results {
+			fmt.Fprintln(s.stdout, result)
+		}
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Fprintln(s.stdout, "database schema is up to date")
+		}
+	case "down":
+		result, err := s.migrations.Down(ctx)
+		if result != nil {
+			fmt.Fprintln(s.stdout, result)
+		}
+		if err != nil {
+			return err
+		}
+	case "status":
+		statuses, err := s.migrations.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.State == goose.StateApplied {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(s.stdout, "%-19s  %s\n", appliedAt, status.Source.Path)
+		}
+	case "version":
+		current, err := s.migrations.GetDBVersion(ctx)
+		if err != nil {
+			return err
+		}
+		sources := s.migrations.ListSources()
+		fmt.Fprintf(s.stdout, "database schema version: %d (latest: %d)\n", current, sources[len(sources)-1].Version)
+	default:
+		return fmt.Errorf("gator migrate: error: argument action: invalid choice: '%s' (choose from 'up', 'down', 'status', 'version')", cmd.arguments[0])
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/a-fleming/gator/internal/database"
+)
+
+// opmlDocument is an OPML 2.0 subscription list.
+type opmlDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    opmlHead `xml:"head"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// opmlOutline is a feed when it has an xmlUrl, and a folder otherwise.
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// opmlFeed is a feed outline with the folders it is nested in, as a
+// folder path.
+type opmlFeed struct {
+	Name   string
+	URL    string
+	Folder string
+}
+
+// A folder path joins folder names with "/". A "/" or "\" in a name is
+// escaped with a backslash, so "AC/DC" nested in "Music" is "Music/AC\/DC".
+const opmlFolderSeparator = "/"
+
+var folderNameEscaper = strings.NewReplacer(`\`, `\\`, opmlFolderSeparator, `\`+opmlFolderSeparator)
+
+// maxFeedNameLength is the length of feeds.name.
+const maxFeedNameLength = 100
+
+// readOPMLFeeds returns the feeds in an OPML document, in document order.
+func readOPMLFeeds(r io.Reader) ([]opmlFeed, error) {
+	var doc opmlDocument
+	err := xml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("parse OPML: %w", err)
+	}
+	var feeds []opmlFeed
+	var walk func(outlines []opmlOutline, folders []string)
+	walk = func(outlines []opmlOutline, folders []string) {
+		for _, outline := range outlines {
+			title := strings.TrimSpace(outline.Title)
+			if len(title) == 0 {
+				title = strings.TrimSpace(outline.Text)
+			}
+			feedURL := strings.TrimSpace(outline.XMLURL)
+			if len(feedURL) == 0 {
+				// a folder; an untitled one doesn't add a level
+				nested := folders
+				if len(title) != 0 {
+					nested = append(slices.Clip(folders), folderNameEscaper.Replace(title))
+				}
+				walk(outline.Outlines, nested)
+				continue
+			}
+			if len(title) == 0 {
+				title = feedURL
+			}
+			feeds = append(feeds, opmlFeed{
+				Name:   title,
+				URL:    feedURL,
+				Folder: strings.Join(folders, opmlFolderSeparator),
+			})
+			// feeds nested under a feed are kept in the same folder
+			walk(outline.Outlines, folders)
+		}
+	}
+	walk(doc.Body.Outlines, nil)
+	return feeds, nil
+}
+
+// writeOPML writes follows as an OPML 2.0 document. Feeds in a folder are
+// nested in an outline per folder level; each level lists its folders and
+// then its feeds, sorted by name.
+func writeOPML(w io.Writer, title string, created time.Time, follows []database.GetFeedFollowsForUserRow) error {
+	feedsByFolder := map[string][]opmlOutline{}
+	folders := map[string]bool{}
+	for _, follow := range follows {
+		path := follow.Folder.String
+		feedsByFolder[path] = append(feedsByFolder[path], opmlOutline{
+			Text:   follow.FeedName,
+			Title:  follow.FeedName,
+			Type:   "rss",
+			XMLURL: follow.FeedUrl,
+		})
+		for ; len(path) != 0; path, _ = splitFolderPath(path) {
+			folders[path] = true
+		}
+	}
+
+	var build func(path string) []opmlOutline
+	build = func(path string) []opmlOutline {
+		var subfolders []string
+		for folder := range folders {
+			parent, _ := splitFolderPath(folder)
+			if parent == path {
+				subfolders = append(subfolders, folder)
+			}
+		}
+		slices.Sort(subfolders)
+		var outlines []opmlOutline
+		for _, folder := range subfolders {
+			_, name := splitFolderPath(folder)
+			outlines = append(outlines, opmlOutline{
+				Text:     name,
+				Title:    name,
+				Outlines: build(folder),
+			})
+		}
+		feeds := feedsByFolder[path]
+		slices.SortStableFunc(feeds, func(a, b opmlOutline) int {
+			return strings.Compare(a.Text, b.Text)
+		})
+		return append(outlines, feeds...)
+	}
+
+	doc := opmlDocument{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:       title,
+			DateCreated: created.Format(time.RFC1123Z),
+		},
+		Body: opmlBody{Outlines: build("")},
+	}
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(doc)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// splitFolderPath splits a folder path into its parent's path and its own
+// unescaped name. A top-level folder's parent is "".
+func splitFolderPath(path string) (string, string) {
+	sep := -1
+	for idx := 0; idx < len(path); idx++ {
+		switch path[idx] {
+		case '\\':
+			// the next byte is escaped
+			idx++
+		case opmlFolderSeparator[0]:
+			sep = idx
+		}
+	}
+	if sep < 0 {
+		return "", unescapeFolderName(path)
+	}
+	return path[:sep], unescapeFolderName(path[sep+len(opmlFolderSeparator):])
+}
+
+func unescapeFolderName(name string) string {
+	var unescaped strings.Builder
+	for idx := 0; idx < len(name); idx++ {
+		if name[idx] == '\\' && idx+1 < len(name) {
+			idx++
+		}
+		unescaped.WriteByte(name[idx])
+	}
+	return unescaped.String()
+}
+
+func handlerExport(s *state, cmd command, user database.User) error {
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	return writeOPML(s.stdout, fmt.Sprintf("gator subscriptions for %s", user.Name), time.Now(), follows)
+}
+
+// opmlImportResult says what importing one feed did.
+type opmlImportResult int
+
+const (
+	opmlAdded opmlImportResult = iota
+	opmlFollowed
+	opmlAlreadyFollowing
+)
+
+func handlerImport(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf("gator import: error: the following argument is required: file")
+	}
+	file, err := os.Open(cmd.arguments[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	feeds, err := readOPMLFeeds(file)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	follows, err := s.db.GetFeedFollowsForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	following := map[string]bool{}
+	for _, follow := range follows {
+		following[follow.FeedUrl] = true
+	}
+
+	counts := map[opmlImportResult]int{}
+	failed := 0
+	for _, feed := range feeds {
+		result, err := importOPMLFeed(ctx, s, user, feed, following[feed.URL])
+		if err != nil {
+			failed++
+			fmt.Fprintf(s.stdout, "failed to import '%s' (%s): %s\n", feed.Name, feed.URL, err)
+			continue
+		}
+		following[feed.URL] = true
+		counts[result]++
+		switch result {
+		case opmlAdded:
+			fmt.Fprintf(s.stdout, "added '%s' (%s)\n", feed.Name, feed.URL)
+		case opmlFollowed:
+			fmt.Fprintf(s.stdout, "followed '%s' (%s)\n", feed.Name, feed.URL)
+		}
+	}
+	fmt.Fprintf(s.stdout, "imported %d feeds: %d added, %d followed, %d already followed, %d failed\n",
+		len(feeds), counts[opmlAdded], counts[opmlFollowed], counts[opmlAlreadyFollowing], failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d feeds could not be imported", failed, len(feeds))
+	}
+	return nil
+}
+
+// importOPMLFeed adds the feed if it is new and follows it unless the user
+// already does, filing it in the feed's folder. A feed outside any folder
+// keeps the folder, if any, that it is already filed in. As with addfeed, a
+// new feed is fetched to validate it before anything is written.
+func importOPMLFeed(ctx context.Context, s *state, user database.User, feed opmlFeed, alreadyFollowing bool) (opmlImportResult, error) {
+	_, err := s.db.GetFeedByUrl(ctx, feed.URL)
+	if errors.Is(classifyDBError(err), ErrNotFound) {
+		err = validateOPMLFeed(ctx, s, feed.URL)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var result opmlImportResult
+	err = s.db.ExecTx(ctx, func(q database.Querier) error {
+		existing, err := q.GetFeedByUrl(ctx, feed.URL)
+		switch {
+		case err == nil:
+			result = opmlFollowed
+		case errors.Is(classifyDBError(err), ErrNotFound):
+			result = opmlAdded
+			name := feed.Name
+			if len([]rune(name)) > maxFeedNameLength {
+				name = string([]rune(name)[:maxFeedNameLength])
+			}
+			existing, err = q.CreateFeed(ctx, database.CreateFeedParams{
+				Name:   name,
+				Url:    feed.URL,
+				UserID: user.ID,
+			})
+			if errors.Is(classifyDBError(err), ErrAlreadyExists) {
+				return newCommandError(ErrAlreadyExists, "a feed named '%s' has already been added; add this one with: gator addfeed <name> %s", name, feed.URL)
+			}
+			if err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		// a failed statement would abort the transaction in Postgres, so
+		// existing follows are skipped rather than tried
+		if alreadyFollowing {
+			result = opmlAlreadyFollowing
+		} else {
+			_, err = q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+				UserID: user.ID,
+				FeedID: existing.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if len(feed.Folder) == 0 {
+			return nil
+		}
+		return q.SetFeedFollowFolder(ctx, database.SetFeedFollowFolderParams{
+			Folder: sql.NullString{String: feed.Folder, Valid: true},
+			UserID: user.ID,
+			FeedID: existing.ID,
+		})
+	})
+	return result, err
+}
+
+// validateOPMLFeed fetches a feed that isn't in the database yet.
+func validateOPMLFeed(ctx context.Context, s *state, feedURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	httpTimeoutSec := 15
+	_, err := s.fetcher.FetchFeed(ctx, feedURL, httpTimeoutSec, "", "")
+	if err != nil {
+		return fmt.Errorf("fetch feed: %w", err)
+	}
+	return nil
+}
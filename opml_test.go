@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-fleming/gator/internal/database"
+)
+
+func TestReadOPMLFeeds(t *testing.T) {
+	feeds, err := readOPMLFeeds(bytes.NewReader(readFixture(t, "subscriptions.opml")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []opmlFeed{
+		{Name: "The Go Blog", URL: "https://go.dev/blog/feed.atom"},
+		{Name: "Hacker News", URL: "https://news.ycombinator.com/rss", Folder: "Tech"},
+		{Name: "Postgres Weekly", URL: "https://postgresweekly.com/rss", Folder: "Tech/Databases"},
+		{Name: "BBC", URL: "https://feeds.bbci.co.uk/news/rss.xml", Folder: "News"},
+	}
+	if !slices.Equal(feeds, want) {
+		t.Errorf("got %+v, want %+v", feeds, want)
+	}
+}
+
+func TestReadOPMLFeedsRejectsInvalid(t *testing.T) {
+	_, err := readOPMLFeeds(strings.NewReader("<opml><body><outline"))
+	if err == nil {
+		t.Error("parsed a truncated document")
+	}
+}
+
+func TestWriteOPML(t *testing.T) {
+	follows := []database.GetFeedFollowsForUserRow{
+		{FeedName: "Zed", FeedUrl: "https://example.com/zed.xml"},
+		{FeedName: "Postgres Weekly", FeedUrl: "https://postgresweekly.com/rss", Folder: sql.NullString{String: "Tech/Databases", Valid: true}},
+		{FeedName: "Alpha", FeedUrl: "https://example.com/alpha.xml"},
+		{FeedName: "Hacker News", FeedUrl: "https://news.ycombinator.com/rss", Folder: sql.NullString{String: "Tech", Valid: true}},
+	}
+	var out bytes.Buffer
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	err := writeOPML(&out, "subscriptions", created, follows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := xml.Header + `<opml version="2.0">
+  <head>
+    <title>subscriptions</title>
+    <dateCreated>Wed, 01 May 2024 12:00:00 +0000</dateCreated>
+  </head>
+  <body>
+    <outline text="Tech" title="Tech">
+      <outline text="Databases" title="Databases">
+        <outline text="Postgres Weekly" title="Postgres Weekly" type="rss" xmlUrl="https://postgresweekly.com/rss"></outline>
+      </outline>
+      <outline text="Hacker News" title="Hacker News" type="rss" xmlUrl="https://news.ycombinator.com/rss"></outline>
+    </outline>
+    <outline text="Alpha" title="Alpha" type="rss" xmlUrl="https://example.com/alpha.xml"></outline>
+    <outline text="Zed" title="Zed" type="rss" xmlUrl="https://example.com/zed.xml"></outline>
+  </body>
+</opml>
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+// subscriptionsFetcher serves the feeds in testdata/subscriptions.opml.
+func subscriptionsFetcher() fakeFetcher {
+	fetcher := fakeFetcher{feeds: map[string]RSSFeed{}}
+	for _, feedURL := range []string{
+		"https://go.dev/blog/feed.atom",
+		"https://news.ycombinator.com/rss",
+		"https://postgresweekly.com/rss",
+		"https://feeds.bbci.co.uk/news/rss.xml",
+	} {
+		fetcher.feeds[feedURL] = testFeed(feedURL)
+	}
+	return fetcher
+}
+
+func TestImportExportRoundTrip(t *testing.T) {
+	s, store, stdout := newTestState(t, subscriptionsFetcher(), "")
+	bob := loginTestUser(t, s, "bob")
+	alice := loginTestUser(t, s, "alice")
+	ctx := context.Background()
+	// one feed was already added by someone else, and one is already
+	// followed in another folder
+	_, err := store.CreateFeed(ctx, database.CreateFeedParams{Name: "BBC News", Url: "https://feeds.bbci.co.uk/news/rss.xml", UserID: bob.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	goBlog, err := store.CreateFeed(ctx, database.CreateFeedParams{Name: "The Go Blog", Url: "https://go.dev/blog/feed.atom", UserID: alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CreateFeedFollow(ctx, database.CreateFeedFollowParams{UserID: alice.ID, FeedID: goBlog.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.SetFeedFollowFolder(ctx, database.SetFeedFollowFolderParams{Folder: nullString("Go"), UserID: alice.ID, FeedID: goBlog.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join("testdata", "subscriptions.opml")
+	err = handlerImport(s, command{name: "import", arguments: []string{path}}, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "imported 4 feeds: 2 added, 1 followed, 1 already followed, 0 failed\n") {
+		t.Errorf("import printed %q", stdout.String())
+	}
+	// importing again changes nothing
+	stdout.Reset()
+	err = handlerImport(s, command{name: "import", arguments: []string{path}}, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "imported 4 feeds: 0 added, 0 followed, 4 already followed, 0 failed\n") {
+		t.Errorf("second import printed %q", stdout.String())
+	}
+
+	stdout.Reset()
+	err = handlerExport(s, command{name: "export"}, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := readOPMLFeeds(bytes.NewReader(stdout.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// feeds keep the names they already had in the database, and a feed
+	// outside any folder in the file keeps its folder
+	want := []opmlFeed{
+		{Name: "The Go Blog", URL: "https://go.dev/blog/feed.atom", Folder: "Go"},
+		{Name: "BBC News", URL: "https://feeds.bbci.co.uk/news/rss.xml", Folder: "News"},
+		{Name: "Postgres Weekly", URL: "https://postgresweekly.com/rss", Folder: "Tech/Databases"},
+		{Name: "Hacker News", URL: "https://news.ycombinator.com/rss", Folder: "Tech"},
+	}
+	if !slices.Equal(exported, want) {
+		t.Errorf("exported %+v, want %+v", exported, want)
+	}
+
+	// the exported file imports cleanly for another user
+	exportPath := filepath.Join(t.TempDir(), "export.opml")
+	err = os.WriteFile(exportPath, stdout.Bytes(), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	err = handlerImport(s, command{name: "import", arguments: []string{exportPath}}, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "imported 4 feeds: 0 added, 4 followed, 0 already followed, 0 failed\n") {
+		t.Errorf("importing the export printed %q", stdout.String())
+	}
+	follows, err := store.GetFeedFollowsForUser(ctx, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reimported []opmlFeed
+	for _, follow := range follows {
+		reimported = append(reimported, opmlFeed{Name: follow.FeedName, URL: follow.FeedUrl, Folder: follow.Folder.String})
+	}
+	sortFeeds := func(feeds []opmlFeed) {
+		slices.SortFunc(feeds, func(a, b opmlFeed) int { return strings.Compare(a.URL, b.URL) })
+	}
+	sortFeeds(reimported)
+	sortFeeds(want)
+	if !slices.Equal(reimported, want) {
+		t.Errorf("after a round trip bob follows %+v, want %+v", reimported, want)
+	}
+}
+
+func TestImportReportsFailedFeeds(t *testing.T) {
+	s, store, stdout := newTestState(t, subscriptionsFetcher(), "")
+	alice := loginTestUser(t, s, "alice")
+	_, err := store.CreateFeed(context.Background(), database.CreateFeedParams{Name: "BBC", Url: "https://example.com/other.xml", UserID: alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = handlerImport(s, command{name: "import", arguments: []string{filepath.Join("testdata", "subscriptions.opml")}}, alice)
+	if err == nil {
+		t.Fatal("import succeeded although a feed name was taken")
+	}
+	if !strings.Contains(stdout.String(), "failed to import 'BBC' (https://feeds.bbci.co.uk/news/rss.xml)") ||
+		!strings.Contains(stdout.String(), "3 added, 0 followed, 0 already followed, 1 failed") {
+		t.Errorf("import printed %q", stdout.String())
+	}
+	_, err = store.GetFeedByUrl(context.Background(), "https://feeds.bbci.co.uk/news/rss.xml")
+	if err == nil {
+		t.Error("the feed that failed to import was stored")
+	}
+}
+
+func TestImportValidatesNewFeeds(t *testing.T) {
+	fetcher := subscriptionsFetcher()
+	delete(fetcher.feeds, "https://postgresweekly.com/rss")
+	s, store, stdout := newTestState(t, fetcher, "")
+	alice := loginTestUser(t, s, "alice")
+
+	err := handlerImport(s, command{name: "import", arguments: []string{filepath.Join("testdata", "subscriptions.opml")}}, alice)
+	if err == nil {
+		t.Fatal("import succeeded although a feed could not be fetched")
+	}
+	if !strings.Contains(stdout.String(), "failed to import 'Postgres Weekly' (https://postgresweekly.com/rss): fetch feed: unexpected HTTP status 404") ||
+		!strings.Contains(stdout.String(), "3 added, 0 followed, 0 already followed, 1 failed") {
+		t.Errorf("import printed %q", stdout.String())
+	}
+	_, err = store.GetFeedByUrl(context.Background(), "https://postgresweekly.com/rss")
+	if err == nil {
+		t.Error("the feed that could not be fetched was stored")
+	}
+}
+
+func TestOPMLFolderNamesWithSeparator(t *testing.T) {
+	document := `<opml version="2.0"><body>
+  <outline text="AC/DC">
+    <outline text="Live \ Studio">
+      <outline text="Blog" xmlUrl="https://example.com/feed.xml"/>
+    </outline>
+  </outline>
+</body></opml>`
+	feeds, err := readOPMLFeeds(strings.NewReader(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []opmlFeed{{Name: "Blog", URL: "https://example.com/feed.xml", Folder: `AC\/DC/Live \\ Studio`}}
+	if !slices.Equal(feeds, want) {
+		t.Fatalf("got %+v, want %+v", feeds, want)
+	}
+
+	var out bytes.Buffer
+	follows := []database.GetFeedFollowsForUserRow{
+		{FeedName: "Blog", FeedUrl: "https://example.com/feed.xml", Folder: nullString(feeds[0].Folder)},
+	}
+	err = writeOPML(&out, "subscriptions", time.Now(), follows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc opmlDocument
+	err = xml.Unmarshal(out.Bytes(), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Body.Outlines) != 1 || doc.Body.Outlines[0].Text != "AC/DC" ||
+		len(doc.Body.Outlines[0].Outlines) != 1 || doc.Body.Outlines[0].Outlines[0].Text != `Live \ Studio` {
+		t.Errorf("exported %s, want the folder names kept whole", out.String())
+	}
+	exported, err := readOPMLFeeds(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(exported, want) {
+		t.Errorf("after a round trip got %+v, want %+v", exported, want)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// pubDateLayouts are tried in order against a date that has been through
+// normalizePubDate, so they need no weekdays, commas or named zones.
+var pubDateLayouts = []string{
+	// RFC 822 / RFC 1123 and their common variants
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -07:00",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04 -07:00",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 Jan 2006 15:04:05.999999999 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 06 15:04:05",
+	"2 Jan 2006",
+	"Jan 2 2006 15:04:05 -0700",
+	"Jan 2 2006 15:04:05 MST",
+	"Jan 2 2006 15:04:05",
+	"Jan 2 2006 15:04",
+	"Jan 2 2006",
+	// ANSI C and Unix date(1)
+	"Jan 2 15:04:05 2006",
+	"Jan 2 15:04:05 MST 2006",
+	"Jan 2 15:04:05 -0700 2006",
+	// RFC 3339 / ISO 8601 / W3C-DTF (dc:date)
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999-0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05.999999999 MST",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006-01",
+	"20060102T150405Z0700",
+	"20060102",
+	"2006",
+}
+
+// pubDateZones maps the zone names publishers commonly use to numeric
+// offsets. time.Parse accepts unknown abbreviations but silently treats
+// them as UTC, which puts US feeds hours out.
+var pubDateZones = map[string]string{
+	"Z":    "+0000",
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"AKST": "-0900",
+	"AKDT": "-0800",
+	"HST":  "-1000",
+	"WET":  "+0000",
+	"WEST": "+0100",
+	"BST":  "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"MET":  "+0100",
+	"MEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"MSK":  "+0300",
+	"IST":  "+0530",
+	"SGT":  "+0800",
+	"HKT":  "+0800",
+	"JST":  "+0900",
+	"KST":  "+0900",
+	"AWST": "+0800",
+	"ACST": "+0930",
+	"ACDT": "+1030",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+	"NZST": "+1200",
+	"NZDT": "+1300",
+}
+
+var pubDateMonths = map[string]string{
+	"january":   "Jan",
+	"february":  "Feb",
+	"march":     "Mar",
+	"april":     "Apr",
+	"june":      "Jun",
+	"july":      "Jul",
+	"august":    "Aug",
+	"sept":      "Sep",
+	"september": "Sep",
+	"october":   "Oct",
+	"november":  "Nov",
+	"december":  "Dec",
+}
+
+var pubDateWeekdays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+var (
+	// a trailing comment such as "(EST)" or "(Coordinated Universal Time)"
+	pubDateComment = regexp.MustCompile(`\s*\([^)]*\)\s*$`)
+	// a zone written as "GMT+2", "UTC-05:00" or "+02"
+	pubDateShortOffset = regexp.MustCompile(`^(?:GMT|UTC|UT)?([+-])(\d{1,2}):?(\d{2})?$`)
+)
+
+// parsePubDate parses a publication date in any of the formats seen in
+// RSS, Atom, RDF and JSON feeds, including the malformed variants
+// publishers produce. Dates with no zone are taken to be UTC.
+func parsePubDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		return time.Time{}, fmt.Errorf("empty publication date")
+	}
+	for _, layout := range []string{time.RFC1123Z, time.RFC3339} {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+
+	normalized := normalizePubDate(value)
+	for _, layout := range pubDateLayouts {
+		parsed, err := time.Parse(layout, normalized)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized publication date %q", value)
+}
+
+// normalizePubDate rewrites a date into a canonical shape: no weekday,
+// no commas, single spaces, abbreviated English month names and numeric
+// zone offsets.
+func normalizePubDate(value string) string {
+	value = pubDateComment.ReplaceAllString(value, "")
+	value = strings.ReplaceAll(value, ",", " ")
+	fields := strings.Fields(value)
+	if len(fields) > 0 && isWeekday(fields[0]) {
+		fields = fields[1:]
+	}
+	for idx, field := range fields {
+		// "Jan." and "2nd" style tokens
+		field = strings.TrimSuffix(field, ".")
+		if month, ok := pubDateMonths[strings.ToLower(field)]; ok {
+			field = month
+		}
+		field = trimOrdinalSuffix(field)
+		if offset, ok := pubDateZones[strings.ToUpper(field)]; ok && idx > 0 {
+			// Unix date(1) puts the zone before the year
+			field = offset
+		} else if idx == len(fields)-1 && idx > 0 {
+			field = numericZone(field)
+		}
+		fields[idx] = field
+	}
+	return strings.Join(fields, " ")
+}
+
+func isWeekday(field string) bool {
+	field = strings.ToLower(strings.TrimSuffix(field, "."))
+	if len(field) < 3 {
+		return false
+	}
+	for _, weekday := range pubDateWeekdays {
+		if strings.HasPrefix(field, weekday) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimOrdinalSuffix turns "1st", "2nd", "3rd" and "4th" into plain numbers.
+func trimOrdinalSuffix(field string) string {
+	if len(field) < 3 || field[0] < '0' || field[0] > '9' {
+		return field
+	}
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		trimmed, found := strings.CutSuffix(strings.ToLower(field), suffix)
+		if found && len(strings.Trim(trimmed, "0123456789")) == 0 {
+			return trimmed
+		}
+	}
+	return field
+}
+
+// numericZone converts a short or GMT-relative offset such as "+2" or
+// "UTC-05:00" to the -0700 style. Anything it doesn't recognise is
+// returned unchanged.
+func numericZone(field string) string {
+	match := pubDateShortOffset.FindStringSubmatch(strings.ToUpper(field))
+	if match == nil {
+		return field
+	}
+	hours := match[2]
+	if len(hours) == 1 {
+		hours = "0" + hours
+	}
+	minutes := match[3]
+	if len(minutes) == 0 {
+		minutes = "00"
+	}
+	return match[1] + hours + minutes
+}
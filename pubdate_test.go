@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePubDate(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	}
+	tests := []struct {
+		name  string
+		value string
+		want  time.Time
+	}{
+		// RFC 1123 and RFC 822
+		{"RFC1123Z", "Mon, 02 Jan 2006 15:04:05 -0700", utc(2006, 1, 2, 22, 4, 5)},
+		{"RFC1123 GMT", "Mon, 02 Jan 2006 15:04:05 GMT", utc(2006, 1, 2, 15, 4, 5)},
+		{"RFC1123 UT", "Mon, 02 Jan 2006 15:04:05 UT", utc(2006, 1, 2, 15, 4, 5)},
+		{"RFC1123 Z", "Mon, 02 Jan 2006 15:04:05 Z", utc(2006, 1, 2, 15, 4, 5)},
+		{"RFC822 two-digit year", "Mon, 02 Jan 06 15:04:05 -0700", utc(2006, 1, 2, 22, 4, 5)},
+		{"RFC822 two-digit year named zone", "02 Jan 06 15:04:05 EST", utc(2006, 1, 2, 20, 4, 5)},
+		{"RFC822 no seconds", "Mon, 02 Jan 2006 15:04 +0000", utc(2006, 1, 2, 15, 4, 0)},
+		{"single-digit day", "Tue, 3 Jan 2006 15:04:05 +0000", utc(2006, 1, 3, 15, 4, 5)},
+		{"colon in offset", "Mon, 02 Jan 2006 15:04:05 +02:00", utc(2006, 1, 2, 13, 4, 5)},
+
+		// named zones are not taken as UTC
+		{"EST", "Mon, 02 Jan 2006 15:04:05 EST", utc(2006, 1, 2, 20, 4, 5)},
+		{"EDT", "Sun, 02 Jul 2006 15:04:05 EDT", utc(2006, 7, 2, 19, 4, 5)},
+		{"CST", "Mon, 02 Jan 2006 15:04:05 CST", utc(2006, 1, 2, 21, 4, 5)},
+		{"PST", "Mon, 02 Jan 2006 15:04:05 PST", utc(2006, 1, 2, 23, 4, 5)},
+		{"PDT", "Sun, 02 Jul 2006 15:04:05 PDT", utc(2006, 7, 2, 22, 4, 5)},
+		{"CEST", "Sun, 02 Jul 2006 15:04:05 CEST", utc(2006, 7, 2, 13, 4, 5)},
+		{"IST", "Mon, 02 Jan 2006 15:04:05 IST", utc(2006, 1, 2, 9, 34, 5)},
+		{"JST", "Mon, 02 Jan 2006 15:04:05 JST", utc(2006, 1, 2, 6, 4, 5)},
+		{"AEDT", "Mon, 02 Jan 2006 15:04:05 AEDT", utc(2006, 1, 2, 4, 4, 5)},
+		{"lower-case zone", "Mon, 02 Jan 2006 15:04:05 pst", utc(2006, 1, 2, 23, 4, 5)},
+
+		// RFC 3339, ISO 8601 and W3C-DTF as used by Atom and dc:date
+		{"RFC3339 Z", "2006-01-02T15:04:05Z", utc(2006, 1, 2, 15, 4, 5)},
+		{"RFC3339 offset", "2006-01-02T15:04:05-07:00", utc(2006, 1, 2, 22, 4, 5)},
+		{"RFC3339 fraction", "2006-01-02T15:04:05.123Z", time.Date(2006, 1, 2, 15, 4, 5, 123000000, time.UTC)},
+		{"ISO8601 offset without colon", "2006-01-02T15:04:05+0100", utc(2006, 1, 2, 14, 4, 5)},
+		{"ISO8601 basic format", "20060102T150405Z", utc(2006, 1, 2, 15, 4, 5)},
+		{"dc:date minutes only", "2006-01-02T15:04Z", utc(2006, 1, 2, 15, 4, 0)},
+		{"dc:date no zone", "2006-01-02T15:04:05", utc(2006, 1, 2, 15, 4, 5)},
+		{"dc:date day", "2006-01-02", utc(2006, 1, 2, 0, 0, 0)},
+		{"dc:date month", "2006-01", utc(2006, 1, 1, 0, 0, 0)},
+		{"dc:date year", "2006", utc(2006, 1, 1, 0, 0, 0)},
+		{"space-separated", "2006-01-02 15:04:05 +00:00", utc(2006, 1, 2, 15, 4, 5)},
+		{"space-separated no zone", "2006-01-02 15:04:05", utc(2006, 1, 2, 15, 4, 5)},
+		{"space-separated Z", "2006-01-02 15:04:05Z", utc(2006, 1, 2, 15, 4, 5)},
+
+		// missing weekday
+		{"no weekday", "02 Jan 2006 15:04:05 -0700", utc(2006, 1, 2, 22, 4, 5)},
+		{"no weekday no zone", "02 Jan 2006 15:04:05", utc(2006, 1, 2, 15, 4, 5)},
+		{"date only", "02 Jan 2006", utc(2006, 1, 2, 0, 0, 0)},
+
+		// malformed variants
+		{"wrong weekday", "Fri, 02 Jan 2006 15:04:05 +0000", utc(2006, 1, 2, 15, 4, 5)},
+		{"full weekday", "Monday, 02 Jan 2006 15:04:05 +0000", utc(2006, 1, 2, 15, 4, 5)},
+		{"no comma after weekday", "Mon 02 Jan 2006 15:04:05 +0000", utc(2006, 1, 2, 15, 4, 5)},
+		{"full month name", "Mon, 02 January 2006 15:04:05 +0000", utc(2006, 1, 2, 15, 4, 5)},
+		{"abbreviated month with dot", "Mon, 02 Jan. 2006 15:04:05 +0000", utc(2006, 1, 2, 15, 4, 5)},
+		{"Sept", "Sat, 02 Sept 2006 15:04:05 +0000", utc(2006, 9, 2, 15, 4, 5)},
+		{"extra whitespace", "  Mon,  02   Jan 2006  15:04:05   +0000  ", utc(2006, 1, 2, 15, 4, 5)},
+		{"GMT offset", "Mon, 02 Jan 2006 15:04:05 GMT+2", utc(2006, 1, 2, 13, 4, 5)},
+		{"UTC offset with colon", "Mon, 02 Jan 2006 15:04:05 UTC-05:00", utc(2006, 1, 2, 20, 4, 5)},
+		{"short offset", "Mon, 02 Jan 2006 15:04:05 +02", utc(2006, 1, 2, 13, 4, 5)},
+		{"trailing zone comment", "Mon, 02 Jan 2006 15:04:05 -0500 (EST)", utc(2006, 1, 2, 20, 4, 5)},
+		{"trailing long comment", "Mon, 02 Jan 2006 15:04:05 GMT (Coordinated Universal Time)", utc(2006, 1, 2, 15, 4, 5)},
+		{"month first", "January 2, 2006 15:04:05 -0700", utc(2006, 1, 2, 22, 4, 5)},
+		{"month first date only", "Jan 2nd, 2006", utc(2006, 1, 2, 0, 0, 0)},
+		{"ordinal day", "Mon, 2nd Jan 2006 15:04:05 +0000", utc(2006, 1, 2, 15, 4, 5)},
+		{"ANSI C", "Mon Jan  2 15:04:05 2006", utc(2006, 1, 2, 15, 4, 5)},
+		{"Unix date", "Mon Jan  2 15:04:05 PST 2006", utc(2006, 1, 2, 23, 4, 5)},
+		{"fractional seconds", "Mon, 02 Jan 2006 15:04:05.5 +0000", time.Date(2006, 1, 2, 15, 4, 5, 500000000, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePubDate(tt.value)
+			if err != nil {
+				t.Fatalf("parsePubDate(%q): %v", tt.value, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parsePubDate(%q) = %v, want %v", tt.value, got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePubDateRejects(t *testing.T) {
+	values := []string{
+		"",
+		"   ",
+		"yesterday",
+		"not a date",
+		"Mon, 32 Jan 2006 15:04:05 +0000",
+		"Mon, 02 Foo 2006 15:04:05 +0000",
+		"Mon, 02 Jan 2006 25:04:05 +0000",
+		"2006-13-02T15:04:05Z",
+		"2006-01-02T15:04:05 bogus",
+		"02/01/2006",
+		"1136214245",
+	}
+	for _, value := range values {
+		got, err := parsePubDate(value)
+		if err == nil {
+			t.Errorf("parsePubDate(%q) = %v, want an error", value, got)
+		}
+	}
+}
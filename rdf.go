@@ -0,0 +1,63 @@
+package main
+
+const rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+// RDFFeed is an RSS 1.0 document. Unlike RSS 2.0, the items are siblings
+// of <channel> rather than children of it.
+type RDFFeed struct {
+	Channel struct {
+		Title           string `xml:"title"`
+		Link            string `xml:"link"`
+		Description     string `xml:"description"`
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	} `xml:"channel"`
+	Items []RDFItem `xml:"item"`
+}
+
+type RDFItem struct {
+	About       string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+// toRSSFeed maps an RSS 1.0 document onto the RSS model consumed by addPosts.
+func (f RDFFeed) toRSSFeed() RSSFeed {
+	var feed RSSFeed
+	feed.Channel.Title = f.Channel.Title
+	feed.Channel.Link = f.Channel.Link
+	feed.Channel.Description = f.Channel.Description
+	feed.Channel.UpdatePeriod = f.Channel.UpdatePeriod
+	feed.Channel.UpdateFrequency = f.Channel.UpdateFrequency
+	for _, item := range f.Items {
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			GUID:        item.About,
+			DCDate:      item.Date,
+			DCCreator:   item.Creator,
+			Content:     item.Content,
+		})
+	}
+	applyDublinCore(&feed)
+	return feed
+}
+
+// applyDublinCore fills pubDate and author from dc:date and dc:creator
+// for items that don't set the core RSS elements.
+func applyDublinCore(feed *RSSFeed) {
+	for idx := range feed.Channel.Item {
+		item := &feed.Channel.Item[idx]
+		if len(item.PubDate) == 0 && len(item.DCDate) != 0 {
+			item.PubDate = item.DCDate
+		}
+		if len(item.Author) == 0 {
+			item.Author = item.DCCreator
+		}
+	}
+}
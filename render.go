@@ -0,0 +1,38 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// elements whose text is never shown
+	renderHiddenElements = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)\s*>`)
+	// tags that end a line or a paragraph
+	renderLineBreaks      = regexp.MustCompile(`(?i)<br\s*/?>|</(tr|dt|dd)\s*>`)
+	renderParagraphBreaks = regexp.MustCompile(`(?i)</?(p|div|h[1-6]|ul|ol|table|blockquote|pre|hr)\b[^>]*>`)
+	renderListItems       = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	renderTags            = regexp.MustCompile(`(?s)<[^>]*>`)
+	renderBlankLines      = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText renders an HTML fragment from a feed as plain text for the
+// terminal: block elements become line breaks, list items become bullets
+// and all other markup is dropped.
+func htmlToText(fragment string) string {
+	text := renderHiddenElements.ReplaceAllString(fragment, "")
+	text = renderLineBreaks.ReplaceAllString(text, "\n")
+	text = renderParagraphBreaks.ReplaceAllString(text, "\n\n")
+	text = renderListItems.ReplaceAllString(text, "\n* ")
+	text = renderTags.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	lines := strings.Split(text, "\n")
+	for idx, line := range lines {
+		lines[idx] = strings.Join(strings.Fields(line), " ")
+	}
+	text = strings.Join(lines, "\n")
+	text = renderBlankLines.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
@@ -1,26 +1,37 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
 	"io"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/a-fleming/gator/internal/database"
+	"github.com/a-fleming/gator/internal/metrics"
 	"github.com/google/uuid"
 )
 
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		Title           string    `xml:"title"`
+		Link            string    `xml:"link"`
+		Description     string    `xml:"description"`
+		TTL             string    `xml:"ttl"`
+		SkipHours       []string  `xml:"skipHours>hour"`
+		SkipDays        []string  `xml:"skipDays>day"`
+		UpdatePeriod    string    `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string    `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+		Item            []RSSItem `xml:"item"`
 	} `xml:"channel"`
 }
 
@@ -29,14 +40,58 @@ type RSSItem struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	Author      string `xml:"author"`
+	GUID        string `xml:"guid"`
+	DCDate      string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	DCCreator   string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	// Content is the full body from content:encoded, Atom content or JSON
+	// Feed content_html; Description is often only a teaser.
+	Content        string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Enclosures     []RSSEnclosure `xml:"enclosure"`
+	ITunesDuration string         `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	MediaContent   []MediaContent `xml:"http://search.yahoo.com/mrss/ content"`
 }
 
-func fetchFeed(ctx context.Context, feedURL string, timeoutSec int) (*RSSFeed, error) {
+// fetchResult is the outcome of a conditional GET. Feed is nil when the
+// server answered 304 Not Modified.
+type fetchResult struct {
+	Feed         *RSSFeed
+	StatusCode   int
+	ETag         string
+	LastModified string
+}
+
+// feedFetcher fetches feeds and finds the feeds linked from web pages.
+type feedFetcher interface {
+	FetchFeed(ctx context.Context, feedURL string, timeoutSec int, etag string, lastModified string) (fetchResult, error)
+	DiscoverFeeds(ctx context.Context, pageURL string, timeoutSec int) ([]feedCandidate, error)
+}
+
+// httpFetcher is the feedFetcher used outside tests.
+type httpFetcher struct{}
+
+func (httpFetcher) FetchFeed(ctx context.Context, feedURL string, timeoutSec int, etag string, lastModified string) (fetchResult, error) {
+	return fetchFeed(ctx, feedURL, timeoutSec, etag, lastModified)
+}
+
+func (httpFetcher) DiscoverFeeds(ctx context.Context, pageURL string, timeoutSec int) ([]feedCandidate, error) {
+	return discoverFeeds(ctx, pageURL, timeoutSec)
+}
+
+// fetchFeed requests feedURL, sending If-None-Match and If-Modified-Since
+// when validators from a previous fetch are known.
+func fetchFeed(ctx context.Context, feedURL string, timeoutSec int, etag string, lastModified string) (fetchResult, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
-		return &RSSFeed{}, err
+		return fetchResult{}, err
 	}
 	req.Header.Set("User-Agent", "gator")
+	if len(etag) != 0 {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if len(lastModified) != 0 {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
 
 	client := &http.Client{
 		Timeout: time.Duration(timeoutSec) * time.Second,
@@ -44,72 +99,361 @@ func fetchFeed(ctx context.Context, feedURL string, timeoutSec int) (*RSSFeed, e
 
 	res, err := client.Do(req)
 	if err != nil {
-		return &RSSFeed{}, err
+		return fetchResult{}, err
 	}
 	defer res.Body.Close()
 
+	result := fetchResult{
+		StatusCode:   res.StatusCode,
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+	}
+	if res.StatusCode == http.StatusNotModified {
+		// a 304 may omit validators that haven't changed
+		if len(result.ETag) == 0 {
+			result.ETag = etag
+		}
+		if len(result.LastModified) == 0 {
+			result.LastModified = lastModified
+		}
+		return result, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("unexpected HTTP status %s", res.Status)
+	}
+
 	data, err := io.ReadAll(res.Body)
 	if err != nil {
-		return &RSSFeed{}, err
+		return result, err
 	}
 
-	var feedData RSSFeed
-	err = xml.Unmarshal(data, &feedData)
+	feedData, err := parseFeed(data, res.Header.Get("Content-Type"))
 	if err != nil {
-		return &RSSFeed{}, err
+		metrics.ParseErrors.Inc()
+		return result, err
 	}
 	unescapeAndTrimFeed(&feedData)
-	return &feedData, nil
+	result.Feed = &feedData
+	return result, nil
 }
 
-func printFeed(feed RSSFeed) {
-	fmt.Printf("RSS Feed: %s\n", feed.Channel.Title)
-	fmt.Printf("Link: %s\n", feed.Channel.Link)
-	fmt.Printf("Description: %s\n", feed.Channel.Description)
-	for _, item := range feed.Channel.Item {
-		if len(item.Title) == 0 {
-			continue
+// parseFeed decodes an RSS 2.0, RSS 1.0 (RDF), Atom 1.0 or JSON Feed
+// document into the RSS model. JSON Feed is chosen by Content-Type or a
+// leading '{'; XML formats are chosen by the root element.
+func parseFeed(data []byte, contentType string) (RSSFeed, error) {
+	if isJSONFeed(data, contentType) {
+		return parseJSONFeed(data)
+	}
+	root, err := xmlRootElement(data)
+	if err != nil {
+		return RSSFeed{}, err
+	}
+	switch {
+	case root.Local == "feed" && root.Space == atomNamespace:
+		var atomFeed AtomFeed
+		err = xml.Unmarshal(data, &atomFeed)
+		if err != nil {
+			return RSSFeed{}, err
 		}
+		return atomFeed.toRSSFeed(), nil
+	case root.Local == "RDF" && root.Space == rdfNamespace:
+		var rdfFeed RDFFeed
+		err = xml.Unmarshal(data, &rdfFeed)
+		if err != nil {
+			return RSSFeed{}, err
+		}
+		return rdfFeed.toRSSFeed(), nil
+	case root.Local == "rss":
+		var feedData RSSFeed
+		err = xml.Unmarshal(data, &feedData)
+		if err != nil {
+			return RSSFeed{}, err
+		}
+		applyDublinCore(&feedData)
+		return feedData, nil
+	default:
+		return RSSFeed{}, fmt.Errorf("unsupported feed format: root element <%s>", root.Local)
+	}
+}
 
-		fmt.Printf(" * Title: %s\n", item.Title)
-		fmt.Printf(" * URL:%s\n", item.Link)
-		fmt.Printf(" * PubDate:%s\n", item.PubDate)
-		fmt.Printf(" * Description:%s\n", item.Description)
-		fmt.Println()
+func isJSONFeed(data []byte, contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/feed+json", "application/json":
+		return true
 	}
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	return len(trimmed) > 0 && trimmed[0] == '{'
 }
 
-func scrapeFeeds(ctx context.Context, s *state, timeoutSec int) error {
-	feedDbInfo, err := s.db.GetNextFeedToFetch(ctx)
+func xmlRootElement(data []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("read feed root element: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+// addPostsResult summarizes one feed's items. Skipped items are reported
+// rather than returned as errors so one bad item can't stop the rest.
+type addPostsResult struct {
+	Inserted   int
+	Updated    int
+	Duplicates int
+	Skipped    []skippedItem
+}
+
+type skippedItem struct {
+	Title  string
+	Reason string
+	// Retry is set for items that couldn't be stored, as opposed to items
+	// that are invalid, so they should be fetched again.
+	Retry bool
+}
+
+// retryNeeded reports whether any item was skipped because it couldn't be
+// stored.
+func (r addPostsResult) retryNeeded() bool {
+	for _, skipped := range r.Skipped {
+		if skipped.Retry {
+			return true
+		}
+	}
+	return false
+}
+
+// scrapeFeeds claims a batch of the feeds that are due, fetches them using
+// at most concurrency goroutines, each with its own timeout, and waits for
+// the whole batch to finish. Claiming makes it safe to run several
+// aggregators against the same database. Feed failures are logged and
+// counted in the summary; the error is for failures of the batch itself.
+func scrapeFeeds(ctx context.Context, s *state, opts aggregateOptions) (scrapeSummary, error) {
+	// the lease must outlast the batch, including feeds waiting for a worker
+	rounds := (opts.batchSize + opts.concurrency - 1) / opts.concurrency
+	lease := time.Duration(rounds)*opts.feedTimeout + time.Minute
+	claimParams := database.ClaimFeedsToFetchParams{
+		LeaseSeconds: int32(lease.Seconds()),
+		BatchSize:    int32(opts.batchSize),
+	}
+	if opts.metricsAddr != "" {
+		counts, err := s.db.CountDueFeeds(ctx)
+		if err != nil {
+			return scrapeSummary{}, err
+		}
+		metrics.FeedsDue.Set(float64(counts.Due))
+		metrics.FeedsOverdue.Set(float64(counts.Overdue))
+	}
+	feeds, err := s.db.ClaimFeedsToFetch(ctx, claimParams)
 	if err != nil {
-		return err
+		return scrapeSummary{}, err
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.concurrency)
+	newPosts := make([]int, len(feeds))
+	errs := make([]error, len(feeds))
+	for idx, feed := range feeds {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			feedCtx, cancel := context.WithTimeout(ctx, opts.feedTimeout)
+			defer cancel()
+			newPosts[idx], errs[idx] = scrapeFeed(feedCtx, s, feed, opts.httpTimeoutSec)
+			if errs[idx] != nil {
+				s.logger.Error("feed fetch failed", "feed_id", feed.ID, "url", feed.Url, "error", errs[idx])
+			}
+		}()
 	}
-	rssFeed, err := fetchFeed(ctx, feedDbInfo.Url, timeoutSec)
+	wg.Wait()
+
+	var summary scrapeSummary
+	for idx := range feeds {
+		if errs[idx] != nil {
+			summary.failed++
+			continue
+		}
+		summary.fetched++
+		summary.newPosts += newPosts[idx]
+	}
+	return summary, nil
+}
+
+// recordWriteTimeout bounds the writes that record the outcome of a fetch.
+const recordWriteTimeout = 10 * time.Second
+
+// scrapeFeed fetches one feed, stores its new posts and records the
+// attempt in feed_fetches. It returns the number of new posts.
+func scrapeFeed(ctx context.Context, s *state, feedDbInfo database.Feed, timeoutSec int) (int, error) {
+	started := time.Now()
+	fetched, err := s.fetcher.FetchFeed(ctx, feedDbInfo.Url, timeoutSec, feedDbInfo.Etag.String, feedDbInfo.LastModified.String)
+	metrics.FeedFetchDuration.Observe(time.Since(started).Seconds())
+	statusLabel := "none"
+	if fetched.StatusCode != 0 {
+		statusLabel = strconv.Itoa(fetched.StatusCode)
+	}
+	metrics.FeedFetches.WithLabelValues(statusLabel).Inc()
+	if errors.Is(context.Cause(ctx), errAggregatorStopped) {
+		// not the feed's fault; its claim expires and it is fetched later
+		return 0, fmt.Errorf("fetch feed '%s': %w", feedDbInfo.Name, errAggregatorStopped)
+	}
+	// the fetch may have failed or run long because the feed's deadline
+	// passed, so the outcome is recorded on a context of its own; that
+	// also releases the feed's claim
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordWriteTimeout)
+	defer cancel()
 	if err != nil {
-		return err
+		return 0, recordFailedFetch(writeCtx, s, feedDbInfo, started, fetched.StatusCode, addPostsResult{}, err)
+	}
+	logger := s.logger.With("feed_id", feedDbInfo.ID, "url", feedDbInfo.Url, "status", fetched.StatusCode)
+	if fetched.Feed == nil {
+		logger.Info("feed not modified", "duration", time.Since(started))
+		return 0, recordFetchedFeed(writeCtx, s, feedDbInfo, started, fetched, addPostsResult{})
+	}
+	result, err := addPosts(ctx, s, *fetched.Feed, feedDbInfo.ID)
+	if errors.Is(context.Cause(ctx), errAggregatorStopped) {
+		return 0, fmt.Errorf("store posts for feed '%s': %w", feedDbInfo.Name, errAggregatorStopped)
 	}
-	err = s.db.MarkFeedFetched(ctx, feedDbInfo.ID)
 	if err != nil {
-		return err
+		return 0, recordFailedFetch(writeCtx, s, feedDbInfo, started, fetched.StatusCode, result, fmt.Errorf("store posts: %w", err))
+	}
+	for _, skipped := range result.Skipped {
+		logger.Warn("skipped item", "title", skipped.Title, "reason", skipped.Reason)
+	}
+	logger.Info("feed fetched",
+		"duration", time.Since(started),
+		"new_posts", result.Inserted,
+		"updated_posts", result.Updated,
+		"duplicates", result.Duplicates,
+		"skipped", len(result.Skipped),
+	)
+	if result.retryNeeded() {
+		// without validators the next fetch gets the whole feed again
+		// rather than a 304, so the items that couldn't be stored are
+		// retried
+		fetched.ETag = ""
+		fetched.LastModified = ""
+	}
+	return result.Inserted, recordFetchedFeed(writeCtx, s, feedDbInfo, started, fetched, result)
+}
+
+// recordFetchedFeed logs a successful fetch in feed_fetches and updates
+// the feed to match, in one transaction.
+func recordFetchedFeed(ctx context.Context, s *state, feed database.Feed, started time.Time, fetched fetchResult, result addPostsResult) error {
+	return s.db.ExecTx(ctx, func(q database.Querier) error {
+		err := markFeedFetched(ctx, q, feed, fetched, result.Inserted)
+		if err != nil {
+			return err
+		}
+		return recordFeedFetch(ctx, q, feed.ID, started, fetched.StatusCode, result, nil)
+	})
+}
+
+// recordFailedFetch logs a failed fetch in feed_fetches and, in the same
+// transaction, backs the feed off or disables it after too many
+// consecutive failures, releasing its claim. It returns the error to
+// report for the feed.
+func recordFailedFetch(ctx context.Context, s *state, feed database.Feed, started time.Time, statusCode int, result addPostsResult, fetchErr error) error {
+	failures := int(feed.ConsecutiveFailures) + 1
+	disable := failures >= s.config.FeedFailureThreshold()
+	interval := time.Duration(feed.FetchIntervalSeconds) * time.Second
+	backoff := failureBackoff(interval, failures)
+	delay := nextFetchDelay(backoff, storedHints(feed), time.Now())
+	params := database.MarkFeedFetchFailedParams{
+		LastFetchError:     nullString(fetchErr.Error()),
+		NextFetchInSeconds: int32(delay.Seconds()),
+		Disable:            disable,
+		ID:                 feed.ID,
 	}
-	printFeed(*rssFeed)
-	err = addPosts(ctx, s, *rssFeed, feedDbInfo.ID)
+	err := s.db.ExecTx(ctx, func(q database.Querier) error {
+		err := recordFeedFetch(ctx, q, feed.ID, started, statusCode, result, fetchErr)
+		if err != nil {
+			return err
+		}
+		return q.MarkFeedFetchFailed(ctx, params)
+	})
 	if err != nil {
 		return err
 	}
-	return nil
+	if disable {
+		return fmt.Errorf("fetch feed '%s': %w (disabled after %d consecutive failures)", feed.Name, fetchErr, failures)
+	}
+	return fmt.Errorf("fetch feed '%s': %w", feed.Name, fetchErr)
+}
+
+// markFeedFetched stores the fetch's cache validators and publisher hints,
+// releases the feed's claim and schedules its next fetch.
+func markFeedFetched(ctx context.Context, q database.Querier, feed database.Feed, fetched fetchResult, newPosts int) error {
+	hints := storedHints(feed)
+	if fetched.Feed != nil {
+		hints = feedHints(*fetched.Feed)
+	}
+	interval := nextFetchInterval(feed, newPosts)
+	delay := nextFetchDelay(interval, hints, time.Now())
+	params := database.MarkFeedFetchedParams{
+		Etag:                 nullString(fetched.ETag),
+		LastModified:         nullString(fetched.LastModified),
+		FetchIntervalSeconds: int32(interval.Seconds()),
+		NextFetchInSeconds:   int32(delay.Seconds()),
+		TtlMinutes:           hints.ttlParam(),
+		SkipHours:            hints.skipHours,
+		SkipDays:             hints.skipDays,
+		UpdatePeriod:         nullString(hints.updatePeriod),
+		UpdateFrequency:      hints.updateFrequencyParam(),
+		ID:                   feed.ID,
+	}
+	return q.MarkFeedFetched(ctx, params)
+}
+
+// recordFeedFetch writes one row to feed_fetches, with the number of posts
+// the fetch added and items it skipped. A zero statusCode means no HTTP
+// response was received.
+func recordFeedFetch(ctx context.Context, q database.Querier, feedID uuid.UUID, started time.Time, statusCode int, result addPostsResult, fetchErr error) error {
+	params := database.CreateFeedFetchParams{
+		FeedID: feedID,
+		StatusCode: sql.NullInt32{
+			Int32: int32(statusCode),
+			Valid: statusCode != 0,
+		},
+		DurationMs:   int32(time.Since(started).Milliseconds()),
+		NewPosts:     int32(result.Inserted),
+		SkippedItems: int32(len(result.Skipped)),
+	}
+	if fetchErr != nil {
+		params.Error = nullString(fetchErr.Error())
+	}
+	_, err := q.CreateFeedFetch(ctx, params)
+	return err
 }
 
-func addPosts(ctx context.Context, s *state, feed RSSFeed, feedID uuid.UUID) error {
+func addPosts(ctx context.Context, s *state, feed RSSFeed, feedID uuid.UUID) (addPostsResult, error) {
+	var result addPostsResult
+	fetchedAt := time.Now().UTC()
 	for _, post := range feed.Channel.Item {
 		if len(post.Title) == 0 {
 			continue
 		}
+		if len(post.Link) == 0 {
+			result.Skipped = append(result.Skipped, skippedItem{
+				Title:  post.Title,
+				Reason: "missing link",
+			})
+			continue
+		}
 
-		pubTime, err := time.Parse(time.RFC1123Z, post.PubDate)
+		pubTime, err := parsePubDate(post.PubDate)
 		if err != nil {
-			return err
+			pubTime = fetchedAt
 		}
+		// published_at has no time zone, so the publisher's offset would be
+		// dropped rather than applied
+		pubTime = pubTime.UTC()
 
 		var description sql.NullString
 		if len(post.Description) != 0 {
@@ -122,36 +466,130 @@ func addPosts(ctx context.Context, s *state, feed RSSFeed, feedID uuid.UUID) err
 				Valid: false,
 			}
 		}
-		createPostParams := database.CreatePostParams{
+		// the guid identifies an item within its feed even if its link
+		// changes; items without one are identified by their link
+		guid := post.GUID
+		if len(guid) == 0 {
+			guid = post.Link
+		}
+		upsertPostParams := database.UpsertPostParams{
 			Title:       post.Title,
 			Url:         post.Link,
 			Description: description,
 			PublishedAt: pubTime,
 			FeedID:      feedID,
+			Content:     nullString(post.Content),
+			Guid:        guid,
+			Author:      nullString(post.Author),
 		}
-		postResults, err := s.db.CreatePost(ctx, createPostParams)
-		if err != nil {
-			if err.Error() == "pq: duplicate key value violates unique constraint \"posts_url_key\"" {
-				fmt.Printf("skipping duplicate post\n")
-				continue
-			} else {
+		var postInfo database.UpsertPostRow
+		unchanged := false
+		// the post and its enclosures are stored together, so an item whose
+		// enclosures can't be stored is left out and retried next fetch
+		err = s.db.ExecTx(ctx, func(q database.Querier) error {
+			if guid != post.Link {
+				adoptParams := database.AdoptLegacyPostParams{
+					Guid:   guid,
+					FeedID: feedID,
+					Url:    post.Link,
+				}
+				err := q.AdoptLegacyPost(ctx, adoptParams)
+				if err != nil {
+					return err
+				}
+			}
+			var err error
+			postInfo, err = q.UpsertPost(ctx, upsertPostParams)
+			if errors.Is(err, sql.ErrNoRows) {
+				// already stored and unchanged; an adopted guid is kept
+				unchanged = true
+				return nil
+			}
+			if err != nil {
 				return err
 			}
+			return storeEnclosures(ctx, q, postInfo.ID, post)
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			result.Skipped = append(result.Skipped, skippedItem{
+				Title:  post.Title,
+				Reason: err.Error(),
+				Retry:  true,
+			})
+			continue
+		}
+		if unchanged {
+			result.Duplicates++
+			metrics.PostsDuplicate.Inc()
+			continue
+		}
+		if !postInfo.Inserted {
+			result.Updated++
+			metrics.PostsUpdated.Inc()
+			s.logger.Debug("updated post", "feed_id", feedID, "post_id", postInfo.ID, "url", postInfo.Url)
+			continue
+		}
+		result.Inserted++
+		metrics.PostsInserted.Inc()
+		s.logger.Debug("stored post", "feed_id", feedID, "post_id", postInfo.ID, "url", postInfo.Url)
+	}
+	return result, nil
+}
+
+// storeEnclosures records a stored post's enclosures, updating the type,
+// length and duration of ones that are already known.
+func storeEnclosures(ctx context.Context, q database.Querier, postID uuid.UUID, post RSSItem) error {
+	for _, enclosure := range itemEnclosures(post) {
+		params := database.UpsertEnclosureParams{
+			PostID:   postID,
+			Url:      enclosure.URL,
+			MimeType: nullString(enclosure.Type),
+		}
+		if length, ok := parseEnclosureLength(enclosure.Length); ok {
+			params.LengthBytes = sql.NullInt64{Int64: length, Valid: true}
+		}
+		if duration, ok := parseEnclosureDuration(enclosure.Duration); ok {
+			params.DurationSeconds = sql.NullInt32{Int32: duration, Valid: true}
+		}
+		err := q.UpsertEnclosure(ctx, params)
+		if err != nil {
+			return fmt.Errorf("enclosure '%s': %w", enclosure.URL, err)
 		}
-		fmt.Printf("postResults: %+v\n\n", postResults)
 	}
 	return nil
 }
 
+// nullString maps an empty string to SQL NULL.
+func nullString(value string) sql.NullString {
+	return sql.NullString{
+		String: value,
+		Valid:  len(value) != 0,
+	}
+}
+
 func unescapeAndTrimFeed(feed *RSSFeed) {
 	feed.Channel.Title = strings.TrimSpace(html.UnescapeString(feed.Channel.Title))
 	feed.Channel.Link = strings.TrimSpace(html.UnescapeString(feed.Channel.Link))
 	feed.Channel.Description = strings.TrimSpace(html.UnescapeString(feed.Channel.Description))
+	feed.Channel.TTL = strings.TrimSpace(feed.Channel.TTL)
 	for idx := range feed.Channel.Item {
 		post := &feed.Channel.Item[idx]
 		post.Title = strings.TrimSpace(html.UnescapeString(post.Title))
 		post.Link = strings.TrimSpace(html.UnescapeString(post.Link))
 		post.PubDate = strings.TrimSpace(html.UnescapeString(post.PubDate))
 		post.Description = strings.TrimSpace(html.UnescapeString(post.Description))
+		post.Author = strings.TrimSpace(html.UnescapeString(post.Author))
+		post.GUID = strings.TrimSpace(html.UnescapeString(post.GUID))
+		for idx := range post.Enclosures {
+			post.Enclosures[idx].URL = strings.TrimSpace(post.Enclosures[idx].URL)
+		}
+		for idx := range post.MediaContent {
+			post.MediaContent[idx].URL = strings.TrimSpace(post.MediaContent[idx].URL)
+		}
+		// content is markup already, usually in a CDATA section
+		post.Content = strings.TrimSpace(post.Content)
 	}
 }
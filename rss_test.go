@@ -0,0 +1,704 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-fleming/gator/internal/config"
+	"github.com/a-fleming/gator/internal/database"
+	"github.com/a-fleming/gator/internal/memstore"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func parseFixture(t *testing.T, name string, contentType string) RSSFeed {
+	t.Helper()
+	feed, err := parseFeed(readFixture(t, name), contentType)
+	if err != nil {
+		t.Fatalf("parseFeed(%s): %v", name, err)
+	}
+	unescapeAndTrimFeed(&feed)
+	return feed
+}
+
+func TestParseFeedRSS(t *testing.T) {
+	feed := parseFixture(t, "rss.xml", "application/rss+xml")
+	if feed.Channel.Title != "Example Blog" {
+		t.Errorf("title = %q, want %q", feed.Channel.Title, "Example Blog")
+	}
+	if len(feed.Channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Channel.Item))
+	}
+
+	first := feed.Channel.Item[0]
+	if first.Link != "https://blog.example.com/first" {
+		t.Errorf("first link = %q", first.Link)
+	}
+	if first.GUID != "post-1" {
+		t.Errorf("first guid = %q, want %q", first.GUID, "post-1")
+	}
+	if first.Description != "A short teaser." {
+		t.Errorf("first description = %q", first.Description)
+	}
+	if first.Content != "<p>The <b>full</b> first post.</p>" {
+		t.Errorf("first content = %q", first.Content)
+	}
+	assertPubDate(t, first.PubDate, time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC))
+
+	second := feed.Channel.Item[1]
+	if second.Title != "Second & last" {
+		t.Errorf("second title = %q", second.Title)
+	}
+	if second.Link != "https://blog.example.com/second" {
+		t.Errorf("second link = %q", second.Link)
+	}
+	if second.Description != "<p>Only a description.</p>" {
+		t.Errorf("second description = %q", second.Description)
+	}
+	if second.Content != "" {
+		t.Errorf("second content = %q, want none", second.Content)
+	}
+	// dc:date stands in for a missing pubDate
+	assertPubDate(t, second.PubDate, time.Date(2006, 1, 3, 10, 0, 0, 0, time.UTC))
+}
+
+func TestParseFeedAtom(t *testing.T) {
+	// served with a generic type, so the root element decides
+	feed := parseFixture(t, "atom.xml", "application/xml")
+	if feed.Channel.Title != "Example Releases" {
+		t.Errorf("title = %q", feed.Channel.Title)
+	}
+	if feed.Channel.Link != "https://example.com/releases" {
+		t.Errorf("feed link = %q, want the alternate link", feed.Channel.Link)
+	}
+	if feed.Channel.Description != "Releases of an example project" {
+		t.Errorf("description = %q", feed.Channel.Description)
+	}
+	if len(feed.Channel.Item) != 3 {
+		t.Fatalf("got %d items, want 3", len(feed.Channel.Item))
+	}
+
+	release := feed.Channel.Item[0]
+	if release.Link != "https://example.com/releases/v2.0.0" {
+		t.Errorf("link = %q, want the alternate link rather than self", release.Link)
+	}
+	if release.GUID != "tag:example.com,2024:release-2" {
+		t.Errorf("guid = %q", release.GUID)
+	}
+	if release.Author != "Ada, Grace" {
+		t.Errorf("author = %q, want the entry's authors", release.Author)
+	}
+	if release.Description != "Second major release." {
+		t.Errorf("description = %q, want the summary", release.Description)
+	}
+	if release.Content != "<p>Breaking changes listed below.</p>" {
+		t.Errorf("content = %q", release.Content)
+	}
+	// published wins over updated
+	assertPubDate(t, release.PubDate, time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC))
+	if len(release.Enclosures) != 1 || release.Enclosures[0].URL != "https://example.com/v2.0.0.tar.gz" {
+		t.Errorf("enclosures = %+v", release.Enclosures)
+	}
+
+	noAlternate := feed.Channel.Item[1]
+	if noAlternate.Link != "https://example.com/releases/v1.0.0" {
+		t.Errorf("link = %q, want the http(s) entry id", noAlternate.Link)
+	}
+	if !strings.Contains(noAlternate.Description, "<p>First release.</p>") {
+		t.Errorf("description = %q, want the content when there is no summary", noAlternate.Description)
+	}
+	// updated stands in for a missing published
+	assertPubDate(t, noAlternate.PubDate, time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC))
+
+	noLink := feed.Channel.Item[2]
+	if noLink.Link != "" {
+		t.Errorf("link = %q, want none for a urn id and only a self link", noLink.Link)
+	}
+}
+
+func TestParseFeedJSONFeed(t *testing.T) {
+	feed := parseFixture(t, "jsonfeed-1.1.json", "application/feed+json")
+	if feed.Channel.Title != "Example Podcast" || feed.Channel.Link != "https://podcast.example.com/" {
+		t.Errorf("title = %q, link = %q", feed.Channel.Title, feed.Channel.Link)
+	}
+	if len(feed.Channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Channel.Item))
+	}
+
+	episode := feed.Channel.Item[0]
+	if episode.GUID != "episode-2" || episode.Link != "https://podcast.example.com/episodes/2" {
+		t.Errorf("guid = %q, link = %q", episode.GUID, episode.Link)
+	}
+	if episode.Description != "Guests & news." {
+		t.Errorf("description = %q, want the summary", episode.Description)
+	}
+	if episode.Content != "<p>Full <em>show notes</em>.</p>" {
+		t.Errorf("content = %q, want content_html over content_text", episode.Content)
+	}
+	if episode.Author != "Ada, Grace" {
+		t.Errorf("author = %q, want the named authors", episode.Author)
+	}
+	// date_published wins over date_modified
+	assertPubDate(t, episode.PubDate, time.Date(2024, 4, 1, 7, 0, 0, 0, time.UTC))
+	want := []RSSEnclosure{{URL: "https://podcast.example.com/episode-2.mp3", Type: "audio/mpeg", Length: "24000000", Duration: "1830.5"}}
+	if len(episode.Enclosures) != 1 || episode.Enclosures[0] != want[0] {
+		t.Errorf("enclosures = %+v, want %+v", episode.Enclosures, want)
+	}
+
+	note := feed.Channel.Item[1]
+	if note.Title != "https://podcast.example.com/notes/1" {
+		t.Errorf("title = %q, want the url for an untitled item", note.Title)
+	}
+	if note.Content != "A note without a title." || note.Description != note.Content {
+		t.Errorf("content = %q, description = %q, want content_text for both", note.Content, note.Description)
+	}
+	assertPubDate(t, note.PubDate, time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC))
+}
+
+func TestParseFeedJSONFeed10(t *testing.T) {
+	// JSON Feed 1.0 has a single author; served as plain JSON, the leading
+	// '{' identifies it
+	feed := parseFixture(t, "jsonfeed-1.0.json", "application/json")
+	if len(feed.Channel.Item) != 1 {
+		t.Fatalf("got %d items, want 1", len(feed.Channel.Item))
+	}
+	item := feed.Channel.Item[0]
+	if item.GUID != "https://micro.example.com/2024/05/hello" || item.Title != "Hello" {
+		t.Errorf("guid = %q, title = %q", item.GUID, item.Title)
+	}
+	if item.Author != "Linus" {
+		t.Errorf("author = %q, want the 1.0 author", item.Author)
+	}
+	if item.Content != "<p>Hello, world.</p>" {
+		t.Errorf("content = %q", item.Content)
+	}
+	assertPubDate(t, item.PubDate, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+}
+
+func TestParseFeedRDF(t *testing.T) {
+	feed := parseFixture(t, "rdf.xml", "application/rdf+xml")
+	if feed.Channel.Title != "Example News" || feed.Channel.Link != "https://news.example.org/" {
+		t.Errorf("title = %q, link = %q", feed.Channel.Title, feed.Channel.Link)
+	}
+	if feed.Channel.UpdatePeriod != "hourly" || feed.Channel.UpdateFrequency != "2" {
+		t.Errorf("update period = %q, frequency = %q", feed.Channel.UpdatePeriod, feed.Channel.UpdateFrequency)
+	}
+	// items are siblings of the channel, not children of it
+	if len(feed.Channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Channel.Item))
+	}
+
+	second := feed.Channel.Item[0]
+	if second.GUID != "https://news.example.org/story/2" {
+		t.Errorf("guid = %q, want the rdf:about", second.GUID)
+	}
+	if second.Link != "https://news.example.org/story/2?ref=rss" {
+		t.Errorf("link = %q", second.Link)
+	}
+	if second.Description != "A story with full content." || second.Content != "<p>The <b>whole</b> story.</p>" {
+		t.Errorf("description = %q, content = %q", second.Description, second.Content)
+	}
+	if second.Author != "Ada" {
+		t.Errorf("author = %q, want the dc:creator", second.Author)
+	}
+	assertPubDate(t, second.PubDate, time.Date(2024, 6, 2, 7, 30, 0, 0, time.UTC))
+
+	first := feed.Channel.Item[1]
+	if first.GUID != "https://news.example.org/story/1" || first.Title != "First story" {
+		t.Errorf("guid = %q, title = %q", first.GUID, first.Title)
+	}
+	if first.Author != "" {
+		t.Errorf("author = %q, want none", first.Author)
+	}
+	assertPubDate(t, first.PubDate, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func assertPubDate(t *testing.T, value string, want time.Time) {
+	t.Helper()
+	got, err := parsePubDate(value)
+	if err != nil {
+		t.Errorf("parsePubDate(%q): %v", value, err)
+		return
+	}
+	if !got.Equal(want) {
+		t.Errorf("parsePubDate(%q) = %v, want %v", value, got, want)
+	}
+}
+
+// deadlineStore fails transactions begun on a done context, as a real
+// database does.
+type deadlineStore struct {
+	*memstore.Store
+}
+
+func (d deadlineStore) ExecTx(ctx context.Context, fn func(q database.Querier) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return d.Store.ExecTx(ctx, fn)
+}
+
+func TestScrapeFeedRecordsTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	store := memstore.New()
+	s := &state{
+		config:  &config.Config{},
+		db:      deadlineStore{store},
+		logger:  slog.New(slog.DiscardHandler),
+		fetcher: httpFetcher{},
+	}
+	ctx := context.Background()
+	user, err := store.CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := store.CreateFeed(ctx, database.CreateFeedParams{Name: "slow", Url: server.URL, UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feedCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = scrapeFeed(feedCtx, s, feed, 10)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("scrapeFeed error = %v, want a deadline error", err)
+	}
+
+	fetches, err := store.GetFeedFetchesForFeed(ctx, database.GetFeedFetchesForFeedParams{FeedID: feed.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fetches) != 1 || !fetches[0].Error.Valid {
+		t.Errorf("feed_fetches = %+v, want one failed fetch", fetches)
+	}
+	updated, err := store.GetFeedByUrl(ctx, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ConsecutiveFailures != 1 || !updated.LastFetchError.Valid {
+		t.Errorf("consecutive failures = %d, last error = %v; want the failure recorded", updated.ConsecutiveFailures, updated.LastFetchError)
+	}
+}
+
+func TestAddPostsAdoptsLegacyPosts(t *testing.T) {
+	store := memstore.New()
+	s := &state{
+		config: &config.Config{},
+		db:     store,
+		logger: slog.New(slog.DiscardHandler),
+	}
+	ctx := context.Background()
+	user, err := store.CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := store.CreateFeed(ctx, database.CreateFeedParams{Name: "releases", Url: "https://example.com/releases.atom", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// stored before guids were tracked, so the migration used the link
+	legacy, err := store.UpsertPost(ctx, database.UpsertPostParams{
+		Title:       "v1.0.0",
+		Url:         "https://example.com/releases/v1.0.0",
+		PublishedAt: time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC),
+		FeedID:      feed.ID,
+		Guid:        "https://example.com/releases/v1.0.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fetched RSSFeed
+	fetched.Channel.Item = []RSSItem{{
+		Title:   "v1.0.0",
+		Link:    "https://example.com/releases/v1.0.0",
+		PubDate: "2024-01-15T08:00:00Z",
+		GUID:    "tag:example.com,2024:release-1",
+	}}
+	result, err := addPosts(ctx, s, fetched, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 0 || result.Duplicates != 1 {
+		t.Errorf("inserted %d, duplicates %d; want the legacy post matched", result.Inserted, result.Duplicates)
+	}
+	post, err := store.GetPost(ctx, legacy.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Guid != "tag:example.com,2024:release-1" {
+		t.Errorf("guid = %q, want the item's guid adopted", post.Guid)
+	}
+}
+
+func TestAddPostsStoresAuthor(t *testing.T) {
+	store := memstore.New()
+	s := &state{
+		config: &config.Config{},
+		db:     store,
+		logger: slog.New(slog.DiscardHandler),
+	}
+	ctx := context.Background()
+	user, err := store.CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := store.CreateFeed(ctx, database.CreateFeedParams{Name: "blog", Url: "https://example.com/feed.xml", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fetched RSSFeed
+	fetched.Channel.Item = []RSSItem{{
+		Title:   "Hello",
+		Link:    "https://example.com/hello",
+		PubDate: "2024-05-01T12:00:00Z",
+		GUID:    "hello",
+		Author:  "Ada",
+	}}
+	_, err = addPosts(ctx, s, fetched, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a changed author updates the stored post
+	fetched.Channel.Item[0].Author = "Ada, Grace"
+	result, err := addPosts(ctx, s, fetched, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Updated != 1 {
+		t.Errorf("updated %d posts, want 1", result.Updated)
+	}
+	_, err = store.CreateFeedFollow(ctx, database.CreateFeedFollowParams{UserID: user.ID, FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts, err := store.GetPostsForUser(ctx, database.GetPostsForUserParams{ID: user.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].Author != (sql.NullString{String: "Ada, Grace", Valid: true}) {
+		t.Errorf("posts = %+v, want the updated author", posts)
+	}
+}
+
+// flakyEnclosureStore fails the first enclosure write made in a
+// transaction.
+type flakyEnclosureStore struct {
+	*memstore.Store
+	failed bool
+}
+
+type flakyEnclosureQuerier struct {
+	database.Querier
+	store *flakyEnclosureStore
+}
+
+func (f *flakyEnclosureStore) ExecTx(ctx context.Context, fn func(q database.Querier) error) error {
+	return f.Store.ExecTx(ctx, func(q database.Querier) error {
+		return fn(flakyEnclosureQuerier{Querier: q, store: f})
+	})
+}
+
+func (f flakyEnclosureQuerier) UpsertEnclosure(ctx context.Context, arg database.UpsertEnclosureParams) error {
+	if !f.store.failed {
+		f.store.failed = true
+		return errors.New("connection reset")
+	}
+	return f.Querier.UpsertEnclosure(ctx, arg)
+}
+
+func TestAddPostsRetriesItemWhenEnclosuresFail(t *testing.T) {
+	store := memstore.New()
+	s := &state{
+		config: &config.Config{},
+		db:     &flakyEnclosureStore{Store: store},
+		logger: slog.New(slog.DiscardHandler),
+	}
+	ctx := context.Background()
+	user, err := store.CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := store.CreateFeed(ctx, database.CreateFeedParams{Name: "podcast", Url: "https://example.com/podcast.xml", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CreateFeedFollow(ctx, database.CreateFeedFollowParams{UserID: user.ID, FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetched RSSFeed
+	fetched.Channel.Item = []RSSItem{{
+		Title:      "Episode 1",
+		Link:       "https://example.com/episodes/1",
+		GUID:       "episode-1",
+		Enclosures: []RSSEnclosure{{URL: "https://example.com/episode-1.mp3", Type: "audio/mpeg"}},
+	}}
+
+	result, err := addPosts(ctx, s, fetched, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 0 || len(result.Skipped) != 1 {
+		t.Fatalf("first fetch: inserted %d, skipped %d; want the item skipped", result.Inserted, len(result.Skipped))
+	}
+	posts, err := store.GetPostsForUser(ctx, database.GetPostsForUserParams{ID: user.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 0 {
+		t.Fatalf("first fetch stored %d posts, want the post rolled back", len(posts))
+	}
+
+	result, err = addPosts(ctx, s, fetched, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 1 {
+		t.Fatalf("second fetch: inserted %d, want the item retried", result.Inserted)
+	}
+	posts, err = store.GetPostsForUser(ctx, database.GetPostsForUserParams{ID: user.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enclosures, err := store.GetEnclosuresForPost(ctx, posts[0].PostID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enclosures) != 1 {
+		t.Errorf("got %d enclosures, want 1", len(enclosures))
+	}
+}
+
+// validatingFetcher serves one feed with an ETag, answering 304 when the
+// request's If-None-Match matches it.
+type validatingFetcher struct {
+	feed RSSFeed
+	etag string
+}
+
+func (f validatingFetcher) FetchFeed(ctx context.Context, feedURL string, timeoutSec int, etag string, lastModified string) (fetchResult, error) {
+	if etag == f.etag {
+		return fetchResult{StatusCode: http.StatusNotModified, ETag: etag}, nil
+	}
+	feed := f.feed
+	return fetchResult{StatusCode: http.StatusOK, ETag: f.etag, Feed: &feed}, nil
+}
+
+func (f validatingFetcher) DiscoverFeeds(ctx context.Context, pageURL string, timeoutSec int) ([]feedCandidate, error) {
+	return nil, errors.New("not a page")
+}
+
+func TestScrapeFeedRetriesItemWhenEnclosuresFail(t *testing.T) {
+	store := memstore.New()
+	var fetched RSSFeed
+	fetched.Channel.Item = []RSSItem{{
+		Title:      "Episode 1",
+		Link:       "https://example.com/episodes/1",
+		GUID:       "episode-1",
+		Enclosures: []RSSEnclosure{{URL: "https://example.com/episode-1.mp3", Type: "audio/mpeg"}},
+	}}
+	s := &state{
+		config:  &config.Config{},
+		db:      &flakyEnclosureStore{Store: store},
+		logger:  slog.New(slog.DiscardHandler),
+		fetcher: validatingFetcher{feed: fetched, etag: `"v1"`},
+	}
+	ctx := context.Background()
+	user, err := store.CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := store.CreateFeed(ctx, database.CreateFeedParams{Name: "podcast", Url: "https://example.com/podcast.xml", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newPosts, err := scrapeFeed(ctx, s, feed, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newPosts != 0 {
+		t.Fatalf("first fetch stored %d posts, want the item skipped", newPosts)
+	}
+	feed, err = store.GetFeedByUrl(ctx, feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Etag.Valid {
+		t.Errorf("ETag %q was saved although an item still has to be stored", feed.Etag.String)
+	}
+
+	newPosts, err = scrapeFeed(ctx, s, feed, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newPosts != 1 {
+		t.Fatalf("second fetch stored %d posts, want the skipped item", newPosts)
+	}
+	feed, err = store.GetFeedByUrl(ctx, feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Etag.String != `"v1"` {
+		t.Errorf("ETag %q, want it saved once every item is stored", feed.Etag.String)
+	}
+}
+
+// cancellingStore cancels the feed's context when a post is stored, as
+// if the feed's deadline passed while its posts were being written.
+type cancellingStore struct {
+	*memstore.Store
+	cancel context.CancelFunc
+}
+
+type cancellingQuerier struct {
+	database.Querier
+	cancel context.CancelFunc
+}
+
+func (c cancellingStore) ExecTx(ctx context.Context, fn func(q database.Querier) error) error {
+	return c.Store.ExecTx(ctx, func(q database.Querier) error {
+		return fn(cancellingQuerier{Querier: q, cancel: c.cancel})
+	})
+}
+
+func (c cancellingQuerier) UpsertPost(ctx context.Context, arg database.UpsertPostParams) (database.UpsertPostRow, error) {
+	c.cancel()
+	return database.UpsertPostRow{}, ctx.Err()
+}
+
+func TestScrapeFeedRecordsPostsTimeout(t *testing.T) {
+	store := memstore.New()
+	var fetched RSSFeed
+	fetched.Channel.Item = []RSSItem{{Title: "Post", Link: "https://example.com/post", GUID: "post"}}
+	feedCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &state{
+		config:  &config.Config{},
+		db:      cancellingStore{Store: store, cancel: cancel},
+		logger:  slog.New(slog.DiscardHandler),
+		fetcher: validatingFetcher{feed: fetched, etag: `"v1"`},
+	}
+	ctx := context.Background()
+	user, err := store.CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CreateFeed(ctx, database.CreateFeedParams{Name: "blog", Url: "https://example.com/feed.xml", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimed, err := store.ClaimFeedsToFetch(ctx, database.ClaimFeedsToFetchParams{LeaseSeconds: 600, BatchSize: 1})
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("claimed %d feeds (%v), want the feed", len(claimed), err)
+	}
+
+	_, err = scrapeFeed(feedCtx, s, claimed[0], 5)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("scrapeFeed error = %v, want the context error", err)
+	}
+	fetches, err := store.GetFeedFetchesForFeed(ctx, database.GetFeedFetchesForFeedParams{FeedID: claimed[0].ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fetches) != 1 || !strings.Contains(fetches[0].Error.String, "store posts") {
+		t.Errorf("feed_fetches = %+v, want one failed fetch", fetches)
+	}
+	updated, err := store.GetFeedByUrl(ctx, claimed[0].Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ClaimedUntil.Valid || updated.ConsecutiveFailures != 1 {
+		t.Errorf("claimed until %v with %d failures, want the claim released and the failure counted", updated.ClaimedUntil, updated.ConsecutiveFailures)
+	}
+}
+
+// failingMarkStore fails to update the feed after a fetch.
+type failingMarkStore struct {
+	*memstore.Store
+}
+
+type failingMarkQuerier struct {
+	database.Querier
+}
+
+func (f failingMarkStore) ExecTx(ctx context.Context, fn func(q database.Querier) error) error {
+	return f.Store.ExecTx(ctx, func(q database.Querier) error {
+		return fn(failingMarkQuerier{Querier: q})
+	})
+}
+
+func (f failingMarkQuerier) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error {
+	return errors.New("connection reset")
+}
+
+func (f failingMarkQuerier) MarkFeedFetchFailed(ctx context.Context, arg database.MarkFeedFetchFailedParams) error {
+	return errors.New("connection reset")
+}
+
+func TestScrapeFeedRecordsFetchWithFeedState(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		fetcher feedFetcher
+	}{
+		{name: "fetched", fetcher: validatingFetcher{etag: `"v1"`}},
+		{name: "failed", fetcher: fakeFetcher{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := memstore.New()
+			s := &state{
+				config:  &config.Config{},
+				db:      failingMarkStore{Store: store},
+				logger:  slog.New(slog.DiscardHandler),
+				fetcher: tc.fetcher,
+			}
+			ctx := context.Background()
+			user, err := store.CreateUser(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			feed, err := store.CreateFeed(ctx, database.CreateFeedParams{Name: "blog", Url: "https://example.com/feed.xml", UserID: user.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = scrapeFeed(ctx, s, feed, 5)
+			if err == nil || !strings.Contains(err.Error(), "connection reset") {
+				t.Fatalf("scrapeFeed error = %v, want the failed update", err)
+			}
+			fetches, err := store.GetFeedFetchesForFeed(ctx, database.GetFeedFetchesForFeedParams{FeedID: feed.ID, Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fetches) != 0 {
+				t.Errorf("feed_fetches = %+v, want the fetch rolled back with the feed's update", fetches)
+			}
+		})
+	}
+}
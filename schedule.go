@@ -0,0 +1,176 @@
+package main
+
+import (
+	"database/sql"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-fleming/gator/internal/database"
+)
+
+const (
+	minFetchInterval = 5 * time.Minute
+	maxFetchInterval = 24 * time.Hour
+)
+
+// publisherHints is how often a publisher asks to be polled, from RSS
+// <ttl>, <skipHours> and <skipDays> and the Syndication module's
+// sy:updatePeriod and sy:updateFrequency.
+type publisherHints struct {
+	ttlMinutes      int
+	skipHours       int32 // bit n set: don't poll during hour n, UTC
+	skipDays        int32 // bit n set: don't poll on time.Weekday(n), UTC
+	updatePeriod    string
+	updateFrequency int
+}
+
+var syndicationPeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+var skipDayNames = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// feedHints reads the publisher hints from a fetched feed. Malformed
+// values are ignored.
+func feedHints(feed RSSFeed) publisherHints {
+	var hints publisherHints
+	ttl, err := strconv.Atoi(feed.Channel.TTL)
+	if err == nil && ttl > 0 {
+		hints.ttlMinutes = ttl
+	}
+	for _, hourStr := range feed.Channel.SkipHours {
+		hour, err := strconv.Atoi(strings.TrimSpace(hourStr))
+		if err != nil || hour < 0 || hour > 24 {
+			continue
+		}
+		// some publishers number the hours 1-24
+		hints.skipHours |= 1 << (hour % 24)
+	}
+	for _, dayStr := range feed.Channel.SkipDays {
+		day, ok := skipDayNames[strings.ToLower(strings.TrimSpace(dayStr))]
+		if ok {
+			hints.skipDays |= 1 << day
+		}
+	}
+	period := strings.ToLower(strings.TrimSpace(feed.Channel.UpdatePeriod))
+	if _, ok := syndicationPeriods[period]; ok {
+		hints.updatePeriod = period
+		hints.updateFrequency = 1
+		frequency, err := strconv.Atoi(strings.TrimSpace(feed.Channel.UpdateFrequency))
+		if err == nil && frequency > 0 {
+			hints.updateFrequency = frequency
+		}
+	}
+	return hints
+}
+
+// storedHints reads the publisher hints saved by the last successful fetch.
+func storedHints(feed database.Feed) publisherHints {
+	return publisherHints{
+		ttlMinutes:      int(feed.TtlMinutes.Int32),
+		skipHours:       feed.SkipHours,
+		skipDays:        feed.SkipDays,
+		updatePeriod:    feed.UpdatePeriod.String,
+		updateFrequency: int(feed.UpdateFrequency.Int32),
+	}
+}
+
+// minInterval is the shortest polling interval the publisher asks for,
+// capped at maxFetchInterval so that a feed is still checked daily.
+func (h publisherHints) minInterval() time.Duration {
+	interval := time.Duration(h.ttlMinutes) * time.Minute
+	period, ok := syndicationPeriods[h.updatePeriod]
+	if ok && h.updateFrequency > 0 {
+		interval = max(interval, period/time.Duration(h.updateFrequency))
+	}
+	return min(interval, maxFetchInterval)
+}
+
+// nextAllowed returns the first time at or after t, in UTC, that is not in
+// a skipped hour or on a skipped day. If every hour is skipped the hints
+// are ignored.
+func (h publisherHints) nextAllowed(t time.Time) time.Time {
+	t = t.UTC()
+	candidate := t
+	for range 8 * 24 {
+		switch {
+		case h.skipDays&(1<<candidate.Weekday()) != 0:
+			year, month, day := candidate.Date()
+			candidate = time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+		case h.skipHours&(1<<candidate.Hour()) != 0:
+			candidate = candidate.Truncate(time.Hour).Add(time.Hour)
+		default:
+			return candidate
+		}
+	}
+	return t
+}
+
+func (h publisherHints) ttlParam() sql.NullInt32 {
+	return sql.NullInt32{
+		Int32: int32(h.ttlMinutes),
+		Valid: h.ttlMinutes != 0,
+	}
+}
+
+func (h publisherHints) updateFrequencyParam() sql.NullInt32 {
+	return sql.NullInt32{
+		Int32: int32(h.updateFrequency),
+		Valid: h.updateFrequency != 0,
+	}
+}
+
+// nextFetchInterval returns the interval to use after a successful fetch
+// that produced newPosts posts. Fixed intervals are returned unchanged.
+// Adaptive intervals aim for about one new post per fetch: they shorten
+// for feeds that publish more often than they are polled and lengthen
+// for feeds that had nothing new.
+func nextFetchInterval(feed database.Feed, newPosts int) time.Duration {
+	interval := time.Duration(feed.FetchIntervalSeconds) * time.Second
+	if !feed.AdaptiveInterval {
+		return interval
+	}
+	switch {
+	case newPosts == 0:
+		interval = interval * 3 / 2
+	case newPosts > 1:
+		interval = interval / 2
+	}
+	return min(max(interval, minFetchInterval), maxFetchInterval)
+}
+
+// failureBackoff returns the interval to wait after the given number of
+// consecutive failures: the feed's interval doubled for each failure after
+// the first, capped at maxFetchInterval, with up to 10% jitter either way
+// so that feeds which failed together don't retry together.
+func failureBackoff(interval time.Duration, failures int) time.Duration {
+	backoff := interval
+	for i := 1; i < failures && backoff < maxFetchInterval; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, maxFetchInterval)
+	jitter := time.Duration((rand.Float64()*0.2 - 0.1) * float64(backoff))
+	return backoff + jitter
+}
+
+// nextFetchDelay returns how long to wait before fetching a feed again:
+// at least interval and the publisher's minimum, then moved out of any
+// skipped hours or days.
+func nextFetchDelay(interval time.Duration, hints publisherHints, now time.Time) time.Duration {
+	delay := max(interval, hints.minInterval())
+	return hints.nextAllowed(now.Add(delay)).Sub(now)
+}
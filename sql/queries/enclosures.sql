@@ -0,0 +1,36 @@
+-- name: UpsertEnclosure :exec
+INSERT INTO enclosures (
+    post_id,
+    url,
+    mime_type,
+    length_bytes,
+    duration_seconds
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (post_id, url) DO UPDATE
+SET
+    mime_type = EXCLUDED.mime_type,
+    length_bytes = EXCLUDED.length_bytes,
+    duration_seconds = EXCLUDED.duration_seconds,
+    updated_at = NOW();
+
+-- name: GetEnclosuresForPost :many
+SELECT *
+FROM enclosures
+WHERE post_id = $1
+ORDER BY created_at ASC, url ASC;
+
+-- name: MarkEnclosureDownloaded :exec
+UPDATE enclosures
+SET
+    downloaded_at = NOW(),
+    download_path = $1,
+    sha256 = $2,
+    updated_at = NOW()
+WHERE id = $3;
@@ -0,0 +1,25 @@
+-- name: CreateFeedFetch :one
+INSERT INTO feed_fetches (
+    feed_id,
+    status_code,
+    duration_ms,
+    new_posts,
+    skipped_items,
+    error
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetFeedFetchesForFeed :many
+SELECT *
+FROM feed_fetches
+WHERE feed_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
@@ -21,12 +21,20 @@ JOIN feeds f ON f.id = i.feed_id;
 SELECT
     users.name as user_name,
     feeds.name as feed_name,
-    feeds.url as feed_url
+    feeds.url as feed_url,
+    feed_follows.folder
 FROM feed_follows
 JOIN users ON feed_follows.user_id = users.id
 JOIN feeds ON feed_follows.feed_id = feeds.id
 WHERE users.id = $1;
 
--- name: RemoveFeedFollow :exec
+-- name: RemoveFeedFollow :execrows
 DELETE FROM feed_follows
 WHERE user_id = $1 AND feed_id = $2;
+
+-- name: SetFeedFollowFolder :exec
+UPDATE feed_follows
+SET
+    updated_at = NOW(),
+    folder = sqlc.arg(folder)
+WHERE user_id = sqlc.arg(user_id) AND feed_id = sqlc.arg(feed_id);
@@ -7,6 +7,11 @@ VALUES (
 )
 RETURNING *;
 
+-- name: EnableFeed :exec
+UPDATE feeds
+SET updated_at = NOW(), disabled_at = NULL, consecutive_failures = 0, next_fetch_at = NOW()
+WHERE id = $1;
+
 -- name: GetFeeds :many
 SELECT *
 FROM feeds;
@@ -17,13 +22,72 @@ FROM feeds
 WHERE url = $1
 LIMIT 1;
 
--- name: GetNextFeedToFetch :one
-SELECT *
-FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
+-- name: ClaimFeedsToFetch :many
+-- Leases the unclaimed feeds that are due, most overdue first. SKIP LOCKED
+-- lets concurrent aggregators claim disjoint sets of feeds, and the lease
+-- keeps a feed from being claimed again until it has been marked fetched
+-- or the lease expires.
+UPDATE feeds
+SET claimed_until = NOW() + (sqlc.arg(lease_seconds)::INTEGER * INTERVAL '1 second')
+WHERE id IN (
+    SELECT id
+    FROM feeds
+    WHERE next_fetch_at <= NOW()
+    AND disabled_at IS NULL
+    AND (claimed_until IS NULL OR claimed_until < NOW())
+    ORDER BY next_fetch_at ASC
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
 
 -- name: MarkFeedFetched :exec
+-- Records a successful fetch. A disabled feed keeps the count of failures
+-- it was disabled after, which feed health reports.
+UPDATE feeds
+SET
+    updated_at = NOW(),
+    last_fetched_at = NOW(),
+    last_fetch_error = NULL,
+    etag = sqlc.arg(etag),
+    last_modified = sqlc.arg(last_modified),
+    claimed_until = NULL,
+    fetch_interval_seconds = sqlc.arg(fetch_interval_seconds),
+    next_fetch_at = NOW() + (sqlc.arg(next_fetch_in_seconds)::INTEGER * INTERVAL '1 second'),
+    ttl_minutes = sqlc.arg(ttl_minutes),
+    skip_hours = sqlc.arg(skip_hours),
+    skip_days = sqlc.arg(skip_days),
+    update_period = sqlc.arg(update_period),
+    update_frequency = sqlc.arg(update_frequency),
+    consecutive_failures = CASE WHEN disabled_at IS NULL THEN 0 ELSE consecutive_failures END
+WHERE id = sqlc.arg(id);
+
+-- name: MarkFeedFetchFailed :exec
 UPDATE feeds
-SET updated_at = NOW(), last_fetched_at = NOW()
-WHERE id = $1;
\ No newline at end of file
+SET
+    updated_at = NOW(),
+    last_fetched_at = NOW(),
+    last_fetch_error = sqlc.arg(last_fetch_error),
+    claimed_until = NULL,
+    next_fetch_at = NOW() + (sqlc.arg(next_fetch_in_seconds)::INTEGER * INTERVAL '1 second'),
+    consecutive_failures = consecutive_failures + 1,
+    disabled_at = CASE WHEN sqlc.arg(disable)::BOOLEAN THEN COALESCE(disabled_at, NOW()) ELSE disabled_at END
+WHERE id = sqlc.arg(id);
+
+-- name: SetFeedFetchInterval :exec
+UPDATE feeds
+SET
+    updated_at = NOW(),
+    fetch_interval_seconds = sqlc.arg(fetch_interval_seconds),
+    adaptive_interval = sqlc.arg(adaptive_interval),
+    next_fetch_at = COALESCE(last_fetched_at, NOW()) + (sqlc.arg(fetch_interval_seconds)::INTEGER * INTERVAL '1 second')
+WHERE id = sqlc.arg(id);
+
+-- name: CountDueFeeds :one
+SELECT
+    COUNT(*) FILTER (WHERE next_fetch_at <= NOW()) AS due,
+    COUNT(*) FILTER (
+        WHERE next_fetch_at <= NOW() - (fetch_interval_seconds * INTERVAL '1 second')
+    ) AS overdue
+FROM feeds
+WHERE disabled_at IS NULL;
\ No newline at end of file
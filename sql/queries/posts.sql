@@ -1,19 +1,6 @@
--- name: CreatePost :one
-INSERT INTO posts (
-    title,
-    url,
-    description,
-    published_at,
-    feed_id
-)
-VALUES (
-    $1,
-    $2,
-    $3,
-    $4,
-    $5
-)
-RETURNING *;
+-- name: GetPost :one
+SELECT * FROM posts
+WHERE id = $1;
 
 -- name: GetPostsForUser :many
 SELECT
@@ -24,10 +11,66 @@ SELECT
 	posts.created_at AS created_at,
 	posts.updated_at AS updated_at,
 	posts.published_at AS published_at,
-	posts.feed_id AS feed_id
+	posts.feed_id AS feed_id,
+	posts.content AS content,
+	posts.author AS author
 FROM posts
 JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
 JOIN users ON feed_follows.user_id = users.id
 WHERE users.id = $1
 ORDER BY published_at DESC
 LIMIT $2;
+
+-- name: AdoptLegacyPost :exec
+-- Posts stored before guids were tracked were given their link as their
+-- guid. Gives such a post the item's real guid, unless the feed already
+-- has a post with it, so the item updates the post instead of being
+-- stored again.
+UPDATE posts
+SET guid = $1
+WHERE feed_id = $2
+AND url = $3
+AND guid = url
+AND guid <> $1
+AND NOT EXISTS (
+    SELECT 1
+    FROM posts AS existing
+    WHERE existing.feed_id = $2
+    AND existing.guid = $1
+);
+
+-- name: UpsertPost :one
+-- Stores an item by its feed-scoped guid. A known item is only updated,
+-- bumping updated_at, when its title, link, body or author has changed; otherwise
+-- no row is returned. inserted is true for new posts.
+INSERT INTO posts (
+    title,
+    url,
+    description,
+    published_at,
+    feed_id,
+    content,
+    guid,
+    author
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET
+    title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    author = EXCLUDED.author,
+    updated_at = NOW()
+WHERE (posts.title, posts.url, posts.description, posts.content, posts.author)
+    IS DISTINCT FROM (EXCLUDED.title, EXCLUDED.url, EXCLUDED.description, EXCLUDED.content, EXCLUDED.author)
+RETURNING *, (xmax = 0) AS inserted;
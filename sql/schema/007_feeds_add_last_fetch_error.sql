@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN last_fetch_error TEXT DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN IF EXISTS last_fetch_error;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN etag TEXT DEFAULT NULL,
+ADD COLUMN last_modified TEXT DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN IF EXISTS last_modified,
+DROP COLUMN IF EXISTS etag;
\ No newline at end of file
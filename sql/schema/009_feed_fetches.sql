@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE feed_fetches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    feed_id UUID NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE,
+    status_code INTEGER,
+    duration_ms INTEGER NOT NULL,
+    new_posts INTEGER NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX feed_fetches_feed_id_created_at_idx ON feed_fetches (feed_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE feed_fetches;
\ No newline at end of file
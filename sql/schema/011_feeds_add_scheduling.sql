@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN fetch_interval_seconds INTEGER NOT NULL DEFAULT 3600,
+ADD COLUMN adaptive_interval BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN next_fetch_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+CREATE INDEX feeds_next_fetch_at_idx ON feeds (next_fetch_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS feeds_next_fetch_at_idx;
+
+ALTER TABLE feeds
+DROP COLUMN IF EXISTS next_fetch_at,
+DROP COLUMN IF EXISTS adaptive_interval,
+DROP COLUMN IF EXISTS fetch_interval_seconds;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN ttl_minutes INTEGER DEFAULT NULL,
+ADD COLUMN skip_hours INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN skip_days INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN update_period TEXT DEFAULT NULL,
+ADD COLUMN update_frequency INTEGER DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN IF EXISTS update_frequency,
+DROP COLUMN IF EXISTS update_period,
+DROP COLUMN IF EXISTS skip_days,
+DROP COLUMN IF EXISTS skip_hours,
+DROP COLUMN IF EXISTS ttl_minutes;
\ No newline at end of file
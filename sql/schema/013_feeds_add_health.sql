@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN disabled_at TIMESTAMP DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN IF EXISTS disabled_at,
+DROP COLUMN IF EXISTS consecutive_failures;
\ No newline at end of file
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE enclosures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    post_id UUID NOT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    mime_type TEXT,
+    length_bytes BIGINT,
+    duration_seconds INTEGER,
+    downloaded_at TIMESTAMP,
+    download_path TEXT,
+    sha256 TEXT,
+    UNIQUE (post_id, url)
+);
+
+-- +goose Down
+DROP TABLE enclosures;
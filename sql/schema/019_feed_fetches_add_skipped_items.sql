@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feed_fetches
+ADD COLUMN skipped_items INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE feed_fetches
+DROP COLUMN IF EXISTS skipped_items;
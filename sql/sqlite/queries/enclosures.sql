@@ -0,0 +1,36 @@
+-- name: UpsertEnclosure :exec
+INSERT INTO enclosures (
+    post_id,
+    url,
+    mime_type,
+    length_bytes,
+    duration_seconds
+)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+ON CONFLICT (post_id, url) DO UPDATE
+SET
+    mime_type = excluded.mime_type,
+    length_bytes = excluded.length_bytes,
+    duration_seconds = excluded.duration_seconds,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now');
+
+-- name: GetEnclosuresForPost :many
+SELECT *
+FROM enclosures
+WHERE post_id = ?
+ORDER BY created_at ASC, url ASC;
+
+-- name: MarkEnclosureDownloaded :exec
+UPDATE enclosures
+SET
+    downloaded_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    download_path = ?,
+    sha256 = ?,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?;
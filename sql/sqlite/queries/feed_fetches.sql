@@ -0,0 +1,25 @@
+-- name: CreateFeedFetch :one
+INSERT INTO feed_fetches (
+    feed_id,
+    status_code,
+    duration_ms,
+    new_posts,
+    skipped_items,
+    error
+)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetFeedFetchesForFeed :many
+SELECT *
+FROM feed_fetches
+WHERE feed_id = ?
+ORDER BY created_at DESC
+LIMIT ?;
@@ -0,0 +1,35 @@
+-- name: CreateFeedFollow :one
+-- SQLite has no data-modifying CTEs, so the names are looked up in the
+-- RETURNING clause instead.
+INSERT INTO feed_follows (user_id, feed_id)
+VALUES (?, ?)
+RETURNING
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    feed_id,
+    (SELECT name FROM users WHERE users.id = feed_follows.user_id) AS user_name,
+    (SELECT name FROM feeds WHERE feeds.id = feed_follows.feed_id) AS feed_name;
+
+-- name: GetFeedFollowsForUser :many
+SELECT
+    users.name as user_name,
+    feeds.name as feed_name,
+    feeds.url as feed_url,
+    feed_follows.folder
+FROM feed_follows
+JOIN users ON feed_follows.user_id = users.id
+JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE users.id = ?;
+
+-- name: RemoveFeedFollow :execrows
+DELETE FROM feed_follows
+WHERE user_id = ? AND feed_id = ?;
+
+-- name: SetFeedFollowFolder :exec
+UPDATE feed_follows
+SET
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    folder = sqlc.arg(folder)
+WHERE user_id = sqlc.arg(user_id) AND feed_id = sqlc.arg(feed_id);
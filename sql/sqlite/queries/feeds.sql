@@ -0,0 +1,96 @@
+-- name: CreateFeed :one
+INSERT INTO feeds (name, url, user_id)
+VALUES (
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: EnableFeed :exec
+UPDATE feeds
+SET
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    disabled_at = NULL,
+    consecutive_failures = 0,
+    next_fetch_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?;
+
+-- name: GetFeeds :many
+SELECT *
+FROM feeds;
+
+-- name: GetFeedByUrl :one
+SELECT *
+FROM feeds
+WHERE url = ?
+LIMIT 1;
+
+-- name: ClaimFeedsToFetch :many
+-- Leases the unclaimed feeds that are due, most overdue first. SQLite
+-- allows one writer at a time, so concurrent aggregators can't claim the
+-- same feed, and the lease keeps a feed from being claimed again until it
+-- has been marked fetched or the lease expires.
+UPDATE feeds
+SET claimed_until = strftime('%Y-%m-%d %H:%M:%f', 'now', CAST(sqlc.arg(lease_seconds) AS INTEGER) || ' seconds')
+WHERE id IN (
+    SELECT id
+    FROM feeds
+    WHERE next_fetch_at <= strftime('%Y-%m-%d %H:%M:%f', 'now')
+    AND disabled_at IS NULL
+    AND (claimed_until IS NULL OR claimed_until < strftime('%Y-%m-%d %H:%M:%f', 'now'))
+    ORDER BY next_fetch_at ASC
+    LIMIT sqlc.arg(batch_size)
+)
+RETURNING *;
+
+-- name: MarkFeedFetched :exec
+-- Records a successful fetch. A disabled feed keeps the count of failures
+-- it was disabled after, which feed health reports.
+UPDATE feeds
+SET
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    last_fetched_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    last_fetch_error = NULL,
+    etag = sqlc.arg(etag),
+    last_modified = sqlc.arg(last_modified),
+    claimed_until = NULL,
+    fetch_interval_seconds = sqlc.arg(fetch_interval_seconds),
+    next_fetch_at = strftime('%Y-%m-%d %H:%M:%f', 'now', CAST(sqlc.arg(next_fetch_in_seconds) AS INTEGER) || ' seconds'),
+    ttl_minutes = sqlc.arg(ttl_minutes),
+    skip_hours = sqlc.arg(skip_hours),
+    skip_days = sqlc.arg(skip_days),
+    update_period = sqlc.arg(update_period),
+    update_frequency = sqlc.arg(update_frequency),
+    consecutive_failures = CASE WHEN disabled_at IS NULL THEN 0 ELSE consecutive_failures END
+WHERE id = sqlc.arg(id);
+
+-- name: MarkFeedFetchFailed :exec
+UPDATE feeds
+SET
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    last_fetched_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    last_fetch_error = sqlc.arg(last_fetch_error),
+    claimed_until = NULL,
+    next_fetch_at = strftime('%Y-%m-%d %H:%M:%f', 'now', CAST(sqlc.arg(next_fetch_in_seconds) AS INTEGER) || ' seconds'),
+    consecutive_failures = consecutive_failures + 1,
+    disabled_at = CASE WHEN CAST(sqlc.arg(disable) AS BOOLEAN) THEN COALESCE(disabled_at, strftime('%Y-%m-%d %H:%M:%f', 'now')) ELSE disabled_at END
+WHERE id = sqlc.arg(id);
+
+-- name: SetFeedFetchInterval :exec
+UPDATE feeds
+SET
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    fetch_interval_seconds = sqlc.arg(fetch_interval_seconds),
+    adaptive_interval = sqlc.arg(adaptive_interval),
+    next_fetch_at = strftime('%Y-%m-%d %H:%M:%f', COALESCE(last_fetched_at, 'now'), CAST(sqlc.arg(fetch_interval_seconds) AS INTEGER) || ' seconds')
+WHERE id = sqlc.arg(id);
+
+-- name: CountDueFeeds :one
+SELECT
+    COUNT(*) FILTER (WHERE next_fetch_at <= strftime('%Y-%m-%d %H:%M:%f', 'now')) AS due,
+    COUNT(*) FILTER (
+        WHERE next_fetch_at <= strftime('%Y-%m-%d %H:%M:%f', 'now', (-fetch_interval_seconds) || ' seconds')
+    ) AS overdue
+FROM feeds
+WHERE disabled_at IS NULL;
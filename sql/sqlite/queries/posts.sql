@@ -0,0 +1,89 @@
+-- name: AdoptLegacyPost :exec
+-- Posts stored before guids were tracked were given their link as their
+-- guid. Gives such a post the item's real guid, unless the feed already
+-- has a post with it, so the item updates the post instead of being
+-- stored again.
+UPDATE posts
+SET guid = sqlc.arg(guid)
+WHERE feed_id = sqlc.arg(feed_id)
+AND url = sqlc.arg(url)
+AND guid = url
+AND guid <> sqlc.arg(guid)
+AND NOT EXISTS (
+    SELECT 1
+    FROM posts AS existing
+    WHERE existing.feed_id = sqlc.arg(feed_id)
+    AND existing.guid = sqlc.arg(guid)
+);
+
+-- name: GetPost :one
+SELECT * FROM posts
+WHERE id = ?;
+
+-- name: GetPostsForUser :many
+SELECT
+	posts.title AS title,
+	posts.url AS url,
+	posts.description AS description,
+	posts.id AS post_id,
+	posts.created_at AS created_at,
+	posts.updated_at AS updated_at,
+	posts.published_at AS published_at,
+	posts.feed_id AS feed_id,
+	posts.content AS content,
+	posts.author AS author
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+JOIN users ON feed_follows.user_id = users.id
+WHERE users.id = ?
+ORDER BY published_at DESC
+LIMIT ?;
+
+-- name: InsertPost :one
+-- SQLite can't tell an insert from an update in RETURNING, so the upsert
+-- is split into InsertPost and UpdateChangedPost. No row is returned if
+-- the guid is already stored.
+INSERT INTO posts (
+    title,
+    url,
+    description,
+    published_at,
+    feed_id,
+    content,
+    guid,
+    author
+)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+ON CONFLICT (feed_id, guid) DO NOTHING
+RETURNING *;
+
+-- name: UpdateChangedPost :one
+-- Updates a stored item, bumping updated_at, only if its title, link,
+-- body or author has changed; otherwise no row is returned.
+UPDATE posts
+SET
+    title = sqlc.arg(title),
+    url = sqlc.arg(url),
+    description = sqlc.arg(description),
+    content = sqlc.arg(content),
+    author = sqlc.arg(author),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE feed_id = sqlc.arg(feed_id)
+AND guid = sqlc.arg(guid)
+AND (
+    title IS NOT sqlc.arg(title)
+    OR url IS NOT sqlc.arg(url)
+    OR description IS NOT sqlc.arg(description)
+    OR content IS NOT sqlc.arg(content)
+    OR author IS NOT sqlc.arg(author)
+)
+RETURNING *;
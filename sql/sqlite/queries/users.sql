@@ -0,0 +1,23 @@
+-- name: CreateUser :one
+INSERT INTO users (name)
+VALUES (?)
+RETURNING *;
+
+-- name: GetUser :one
+SELECT *
+FROM users
+WHERE name = ?
+LIMIT 1;
+
+-- name: GetUserById :one
+SELECT *
+FROM users
+WHERE id = ?
+LIMIT 1;
+
+-- name: GetUsers :many
+SELECT *
+FROM users;
+
+-- name: Reset :exec
+DELETE FROM users;
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/a-fleming/gator/internal/database"
+	"github.com/a-fleming/gator/internal/metrics"
+	"github.com/a-fleming/gator/internal/sqlitedb"
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteURLPrefix in db_url selects the SQLite backend. The rest of the
+// URL is the path of the database file, so sqlite:///home/me/gator.db
+// names an absolute path and sqlite://gator.db a relative one.
+const sqliteURLPrefix = "sqlite://"
+
+// sqliteOptions are set on every SQLite connection. Foreign keys are off
+// by default, the busy timeout lets the aggregator and other commands
+// share the file, and times are written in a format SQLite's date
+// functions can compare.
+const sqliteOptions = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+
+// openStorage connects to the database named by dbURL, which is either a
+// Postgres connection URL or a SQLite file, and returns its storage along
+// with the migrations for its engine.
+func openStorage(dbURL string) (database.Storage, *goose.Provider, error) {
+	if path, ok := strings.CutPrefix(dbURL, sqliteURLPrefix); ok {
+		db, err := sql.Open("sqlite", "file:"+path+"?"+sqliteOptions)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = db.Ping()
+		if err != nil {
+			return nil, nil, err
+		}
+		migrations, err := newMigrationProvider(db, goose.DialectSQLite3, "sql/sqlite/schema")
+		if err != nil {
+			return nil, nil, err
+		}
+		return sqlitedb.NewStore(db, func(dbtx sqlitedb.DBTX) sqlitedb.DBTX {
+			return metrics.InstrumentDB(dbtx)
+		}), migrations, nil
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = db.Ping()
+	if err != nil {
+		return nil, nil, err
+	}
+	migrations, err := newMigrationProvider(db, goose.DialectPostgres, "sql/schema")
+	if err != nil {
+		return nil, nil, err
+	}
+	return database.NewStore(db, func(dbtx database.DBTX) database.DBTX {
+		return metrics.InstrumentDB(dbtx)
+	}), migrations, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/a-fleming/gator/internal/database"
+	"github.com/a-fleming/gator/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// observedQueries returns how many times the query named name has been
+// recorded in the DB latency histogram.
+func observedQueries(t *testing.T, name string) uint64 {
+	t.Helper()
+	var metric dto.Metric
+	err := metrics.DBQueryDuration.WithLabelValues(name).(prometheus.Metric).Write(&metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestStorageInstrumentsTransactions(t *testing.T) {
+	db, migrations, err := openStorage(sqliteURLPrefix + filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_, err = migrations.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := observedQueries(t, "CreateUser")
+	_, err = db.CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.ExecTx(ctx, func(q database.Querier) error {
+		_, err := q.CreateUser(ctx, "bob")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := observedQueries(t, "CreateUser") - before; got != 2 {
+		t.Errorf("observed %d CreateUser queries, want 2 including the one in a transaction", got)
+	}
+}